@@ -0,0 +1,14 @@
+package context
+
+import "context"
+
+// WithPrincipal stores the identity of the actor, e.g. user ID, on whose behalf the work is done.
+func WithPrincipal(c context.Context, principal string) context.Context {
+	return context.WithValue(c, PrincipalKey, principal)
+}
+
+func Principal(c context.Context) string {
+	principal, _ := c.Value(PrincipalKey).(string)
+
+	return principal
+}
@@ -0,0 +1,14 @@
+package context
+
+import "context"
+
+// WithRequestID sets ID of the request given by the client, which may differ from the transaction ID.
+func WithRequestID(c context.Context, id string) context.Context {
+	return context.WithValue(c, RequestIDKey, id)
+}
+
+func RequestID(c context.Context) string {
+	id, _ := c.Value(RequestIDKey).(string)
+
+	return id
+}
@@ -0,0 +1,13 @@
+package context
+
+import "context"
+
+func WithTenantID(c context.Context, id string) context.Context {
+	return context.WithValue(c, TenantIDKey, id)
+}
+
+func TenantID(c context.Context) string {
+	id, _ := c.Value(TenantIDKey).(string)
+
+	return id
+}
@@ -0,0 +1,53 @@
+package context
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// TraceID identifies a distributed trace, shared by all services handling it.
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a unit of work within a trace, e.g. handling of a request by a service.
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// TraceContext is the W3C trace context of the current work.
+type TraceContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// ParentSpanID is the span of the caller, invalid if the trace started here.
+	ParentSpanID SpanID
+	Flags        byte
+	// State is vendor specific tracestate, propagated as is.
+	State string
+}
+
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID.IsValid() && tc.SpanID.IsValid()
+}
+
+func WithTrace(c context.Context, tc TraceContext) context.Context {
+	return context.WithValue(c, TraceKey, tc)
+}
+
+func Trace(c context.Context) TraceContext {
+	tc, _ := c.Value(TraceKey).(TraceContext)
+
+	return tc
+}
@@ -10,6 +10,10 @@ type ctxKey uint8
 
 const (
 	TransactionIDKey ctxKey = iota
+	TenantIDKey
+	PrincipalKey
+	TraceKey
+	RequestIDKey
 )
 
 func WithTransactionID(c context.Context, t uuid.UUID) context.Context {
@@ -0,0 +1,294 @@
+// Package health aggregates named checks of application components for liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrCheckTimeout = wherr.Error("health check timed out")
+	ErrCheckPanic   = wherr.Error("health check panicked")
+
+	defaultTimeout  = 5 * time.Second
+	defaultCacheTTL = time.Second
+)
+
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// StatusDegraded means only non-critical checks are failing.
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// CheckFunc returns nil if the component is healthy, e.g. postgres.HealthCheck with bound DB.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status    Status        `json:"status"`
+	Critical  bool          `json:"critical"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type RegistryOpt func(*Registry)
+
+type CheckOpt func(*check)
+
+func NewRegistry(opts ...RegistryOpt) *Registry {
+	r := &Registry{
+		checks:   make(map[string]*check),
+		cacheTTL: defaultCacheTTL,
+	}
+
+	r.ready.Store(true)
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Registry runs registered checks concurrently, results are cached to protect components
+// from frequent probes. It is ready until Shutdown is called.
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[string]*check
+	cacheTTL time.Duration
+	ready    atomic.Bool
+}
+
+// Register adds check under 'name', replacing existing one. Checks are critical by default
+// and run by both liveness and readiness probes, see WithReadinessOnly.
+func (r *Registry) Register(name string, fn CheckFunc, opts ...CheckOpt) {
+	c := &check{
+		fn:       fn,
+		timeout:  defaultTimeout,
+		critical: true,
+		cacheTTL: r.cacheTTL,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = c
+}
+
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.checks, name)
+}
+
+// Check runs all checks for the readiness probe, reusing results not older than their cache TTL.
+func (r *Registry) Check(ctx context.Context) Report {
+	return r.run(ctx, false)
+}
+
+// CheckLiveness runs checks for the liveness probe, skipping ones registered WithReadinessOnly,
+// so failing dependencies don't get the process restarted.
+func (r *Registry) CheckLiveness(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+func (r *Registry) run(ctx context.Context, liveness bool) Report {
+	r.mu.RLock()
+
+	names := make([]string, 0, len(r.checks))
+	checks := make([]*check, 0, len(r.checks))
+
+	for name, c := range r.checks {
+		if liveness && c.readinessOnly {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		checks = append(checks, r.checks[name])
+	}
+
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = c.result(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Ready:  r.Ready(),
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	for i, result := range results {
+		report.Checks[names[i]] = result
+
+		switch {
+		case result.Status == StatusUp:
+		case result.Critical:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) Ready() bool {
+	return r.ready.Load()
+}
+
+func (r *Registry) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// Shutdown marks the registry not ready, so load balancers stop routing new requests.
+// It matches server.BeforeShutdown hook.
+func (r *Registry) Shutdown(_ context.Context) error {
+	r.SetReady(false)
+
+	return nil
+}
+
+type check struct {
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+	cacheTTL time.Duration
+	// readinessOnly checks are skipped by liveness probes
+	readinessOnly bool
+
+	// mu is held while the check runs, so concurrent probes share the result
+	mu     sync.Mutex
+	last   CheckResult
+	cached bool
+}
+
+func (c *check) result(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached && time.Since(c.last.CheckedAt) < c.cacheTTL {
+		return c.last
+	}
+
+	start := time.Now()
+	err := c.run(ctx)
+
+	result := CheckResult{
+		Status:    StatusUp,
+		Critical:  c.critical,
+		Duration:  time.Since(start),
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	// result of the caller giving up is not the state of the component
+	if ctx.Err() == nil {
+		c.last, c.cached = result, true
+	}
+
+	return result
+}
+
+// run waits at most the timeout even if the check ignores its context.
+func (c *check) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errCh <- fmt.Errorf("%w: %v", ErrCheckPanic, p)
+			}
+		}()
+
+		errCh <- c.fn(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ErrCheckTimeout
+	}
+}
+
+// WithCacheTTL sets how long results of checks are reused, 1 second by default.
+func WithCacheTTL(ttl time.Duration) RegistryOpt {
+	return func(r *Registry) {
+		r.cacheTTL = ttl
+	}
+}
+
+// WithTimeout limits duration of the check, 5 seconds by default.
+func WithTimeout(d time.Duration) CheckOpt {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// NonCritical makes failure of the check degrade the status instead of bringing it down.
+func NonCritical() CheckOpt {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// WithReadinessOnly runs the check only for readiness probes, e.g. for databases and other
+// dependencies which a restart of the process would not fix.
+func WithReadinessOnly() CheckOpt {
+	return func(c *check) {
+		c.readinessOnly = true
+	}
+}
+
+// WithCheckCacheTTL overrides cache TTL of the registry for the check.
+func WithCheckCacheTTL(ttl time.Duration) CheckOpt {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	errDown := errors.New("down")
+
+	t.Run("aggregate", func(t *testing.T) {
+		registry := NewRegistry()
+
+		registry.Register("db", func(context.Context) error { return nil })
+		registry.Register("cache", func(context.Context) error { return errDown }, NonCritical())
+
+		report := registry.Check(context.Background())
+		require.Equal(t, StatusDegraded, report.Status)
+		require.True(t, report.Ready)
+		require.Equal(t, StatusUp, report.Checks["db"].Status)
+		require.Equal(t, StatusDown, report.Checks["cache"].Status)
+		require.Equal(t, "down", report.Checks["cache"].Error)
+
+		registry.Register("db", func(context.Context) error { return errDown })
+
+		report = registry.Check(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		registry := NewRegistry()
+
+		registry.Register("slow", func(context.Context) error {
+			time.Sleep(time.Second)
+
+			return nil
+		}, WithTimeout(10*time.Millisecond))
+
+		report := registry.Check(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+		require.Equal(t, ErrCheckTimeout.Error(), report.Checks["slow"].Error)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		registry := NewRegistry()
+
+		registry.Register("panic", func(context.Context) error { panic("boom") })
+
+		report := registry.Check(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+		require.Contains(t, report.Checks["panic"].Error, ErrCheckPanic.Error())
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		var calls atomic.Int32
+
+		registry := NewRegistry(WithCacheTTL(time.Hour))
+
+		registry.Register("cached", func(context.Context) error {
+			calls.Add(1)
+
+			return nil
+		})
+		registry.Register("uncached", func(context.Context) error {
+			calls.Add(10)
+
+			return nil
+		}, WithCheckCacheTTL(0))
+
+		registry.Check(context.Background())
+		registry.Check(context.Background())
+		require.Equal(t, int32(21), calls.Load())
+	})
+
+	t.Run("liveness", func(t *testing.T) {
+		var dbCalls atomic.Int32
+
+		registry := NewRegistry()
+
+		registry.Register("loop", func(context.Context) error { return nil })
+		registry.Register("db", func(context.Context) error {
+			dbCalls.Add(1)
+
+			return errDown
+		}, WithReadinessOnly())
+
+		report := registry.CheckLiveness(context.Background())
+		require.Equal(t, StatusUp, report.Status)
+		require.Contains(t, report.Checks, "loop")
+		require.NotContains(t, report.Checks, "db")
+		require.Zero(t, dbCalls.Load())
+
+		report = registry.Check(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+		require.Equal(t, StatusDown, report.Checks["db"].Status)
+		require.Equal(t, int32(1), dbCalls.Load())
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		registry := NewRegistry()
+
+		require.NoError(t, registry.Shutdown(context.Background()))
+
+		report := registry.Check(context.Background())
+		require.Equal(t, StatusUp, report.Status)
+		require.False(t, report.Ready)
+	})
+}
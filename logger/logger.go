@@ -2,7 +2,12 @@ package logger
 
 import "github.com/google/uuid"
 
-const TransactionKey = "transaction-id"
+const (
+	TransactionKey = "transaction-id"
+	TraceIDKey     = "trace-id"
+	SpanIDKey      = "span-id"
+	RequestIDKey   = "request-id"
+)
 
 type Logger interface {
 	WithLevel(level LogLevel) Logger
@@ -56,9 +56,19 @@ func (l *LogLevel) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// FromCtx returns 'log' with transaction ID, request ID and trace context from ctx, if they are set.
 func FromCtx(ctx context.Context, log Logger) Logger {
-	if transactionID := whctx.TransactionID(ctx); transactionID == uuid.Nil {
-		return log.WithTransaction(transactionID)
+	transactionID := whctx.TransactionID(ctx)
+	if transactionID != uuid.Nil {
+		log = log.WithTransaction(transactionID)
+	}
+
+	if requestID := whctx.RequestID(ctx); requestID != "" && requestID != transactionID.String() {
+		log = log.With(RequestIDKey, requestID)
+	}
+
+	if tc := whctx.Trace(ctx); tc.IsValid() {
+		log = log.With(TraceIDKey, tc.TraceID.String()).With(SpanIDKey, tc.SpanID.String())
 	}
 
 	return log
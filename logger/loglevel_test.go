@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+func TestFromCtx(t *testing.T) {
+	id := uuid.New()
+
+	log := FromCtx(whctx.WithTransactionID(context.Background(), id), &recordLogger{})
+	require.Equal(t, id, log.(*recordLogger).tID)
+
+	log = FromCtx(context.Background(), &recordLogger{})
+	require.Equal(t, &recordLogger{}, log)
+
+	ctx := whctx.WithRequestID(whctx.WithTransactionID(context.Background(), id), "req-42")
+
+	log = FromCtx(ctx, &recordLogger{})
+	require.Equal(t, map[string]any{RequestIDKey: "req-42"}, log.(*recordLogger).args)
+
+	ctx = whctx.WithRequestID(whctx.WithTransactionID(context.Background(), id), id.String())
+
+	log = FromCtx(ctx, &recordLogger{})
+	require.Empty(t, log.(*recordLogger).args)
+
+	tc := whctx.TraceContext{TraceID: whctx.TraceID{1}, SpanID: whctx.SpanID{2}}
+
+	log = FromCtx(whctx.WithTrace(context.Background(), tc), &recordLogger{})
+	require.Equal(t, map[string]any{
+		TraceIDKey: tc.TraceID.String(),
+		SpanIDKey:  tc.SpanID.String(),
+	}, log.(*recordLogger).args)
+}
+
+// recordLogger remembers the transaction and arguments it is derived with.
+type recordLogger struct {
+	NullLogger
+
+	tID  uuid.UUID
+	args map[string]any
+}
+
+func (l *recordLogger) WithTransaction(id uuid.UUID) Logger {
+	return &recordLogger{tID: id, args: l.args}
+}
+
+func (l *recordLogger) With(key string, value any) Logger {
+	args := copyArgs(l.args)
+	args[key] = value
+
+	return &recordLogger{tID: l.tID, args: args}
+}
@@ -73,6 +73,7 @@ func (ptl *PtermLogger) WithError(err error) Logger {
 
 	return &PtermLogger{
 		log:  &log,
+		tID:  ptl.tID,
 		args: args,
 	}
 }
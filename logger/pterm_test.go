@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPtermLoggerKeepsTransaction(t *testing.T) {
+	var buf bytes.Buffer
+
+	id := uuid.New()
+
+	log := NewPtermLogger(Info)
+	log.log = log.log.WithWriter(&buf)
+
+	log.WithTransaction(id).WithError(errors.New("boom")).Errorf("failed")
+
+	require.Contains(t, buf.String(), id.String())
+	require.Contains(t, buf.String(), "boom")
+}
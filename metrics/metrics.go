@@ -0,0 +1,241 @@
+// Package metrics collects counters, gauges and histograms with labels and renders them
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type Kind string
+
+const (
+	KindCounter   Kind = "counter"
+	KindGauge     Kind = "gauge"
+	KindHistogram Kind = "histogram"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets in seconds, suited for request latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10} // nolint: gochecknoglobals
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Registry holds metric families by name. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// Counter returns counter family 'name', creating it on the first call.
+// It panics if 'name' is registered with another kind or labels.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{family: r.family(name, help, KindCounter, nil, labels)}
+}
+
+// Gauge returns gauge family 'name', see Counter.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{family: r.family(name, help, KindGauge, nil, labels)}
+}
+
+// Histogram returns histogram family 'name' with DefaultBuckets if 'buckets' is empty, see Counter.
+// It also panics if 'name' is registered with other buckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &HistogramVec{family: r.family(name, help, KindHistogram, buckets, labels)}
+}
+
+func (r *Registry) family(name, help string, kind Kind, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || !slices.Equal(f.labels, labels) {
+			panic(fmt.Sprintf("metrics: %s is already registered as %s with labels %v", name, f.kind, f.labels))
+		}
+
+		if !slices.Equal(f.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: %s is already registered with buckets %v", name, f.buckets))
+		}
+
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  slices.Clone(labels),
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+
+	r.families[name] = f
+
+	return f
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    Kind
+	labels  []string
+	buckets []float64
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+
+	s = &series{labels: slices.Clone(values)}
+
+	if f.kind == KindHistogram {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+
+	f.series[key] = s
+
+	return s
+}
+
+// series is a single labelled value, histograms use 'value' as the sum of observations.
+type series struct {
+	labels []string
+	value  atomicFloat
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (a *atomicFloat) Add(v float64) {
+	for {
+		old := a.bits.Load()
+		if a.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (a *atomicFloat) Store(v float64) {
+	a.bits.Store(math.Float64bits(v))
+}
+
+func (a *atomicFloat) Load() float64 {
+	return math.Float64frombits(a.bits.Load())
+}
+
+type CounterVec struct {
+	family *family
+}
+
+// With returns counter of label values, in the order of the family labels.
+func (v *CounterVec) With(values ...string) *Counter {
+	return &Counter{series: v.family.with(values)}
+}
+
+type Counter struct {
+	series *series
+}
+
+func (c *Counter) Inc() {
+	c.series.value.Add(1)
+}
+
+// Add increases the counter, negative values are ignored.
+func (c *Counter) Add(v float64) {
+	if v > 0 {
+		c.series.value.Add(v)
+	}
+}
+
+type GaugeVec struct {
+	family *family
+}
+
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return &Gauge{series: v.family.with(values)}
+}
+
+type Gauge struct {
+	series *series
+}
+
+func (g *Gauge) Set(v float64) {
+	g.series.value.Store(v)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.series.value.Add(v)
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+type HistogramVec struct {
+	family *family
+}
+
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return &Histogram{series: v.family.with(values), buckets: v.family.buckets}
+}
+
+type Histogram struct {
+	series  *series
+	buckets []float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.buckets, v)
+
+	h.series.mu.Lock()
+	defer h.series.mu.Unlock()
+
+	if i < len(h.series.counts) {
+		h.series.counts[i]++
+	}
+
+	h.series.count++
+	h.series.value.Add(v)
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		registry := NewRegistry()
+
+		requests := registry.Counter("requests_total", "Number of requests.", "route", "status")
+		requests.With("users", "200").Inc()
+		requests.With("users", "200").Add(2)
+		requests.With("users", "500").Inc()
+		requests.With("orders", "200").Add(-1)
+
+		inFlight := registry.Gauge("in_flight", "Requests\nin flight.")
+		inFlight.With().Inc()
+		inFlight.With().Inc()
+		inFlight.With().Dec()
+
+		latency := registry.Histogram("latency_seconds", "", []float64{1, 0.1}, "route")
+		latency.With(`a"b`).Observe(0.05)
+		latency.With(`a"b`).Observe(0.1)
+		latency.With(`a"b`).Observe(0.5)
+		latency.With(`a"b`).Observe(2)
+
+		registry.Counter("unused_total", "Never incremented.")
+
+		var sb strings.Builder
+
+		require.NoError(t, registry.WriteText(&sb))
+		require.Equal(t, `# HELP in_flight Requests\nin flight.
+# TYPE in_flight gauge
+in_flight 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="a\"b",le="0.1"} 2
+latency_seconds_bucket{route="a\"b",le="1"} 3
+latency_seconds_bucket{route="a\"b",le="+Inf"} 4
+latency_seconds_sum{route="a\"b"} 2.65
+latency_seconds_count{route="a\"b"} 4
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="orders",status="200"} 0
+requests_total{route="users",status="200"} 3
+requests_total{route="users",status="500"} 1
+`, sb.String())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		registry := NewRegistry()
+
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for range 100 {
+					registry.Counter("total", "", "label").With("value").Inc()
+					registry.Histogram("seconds", "", nil).With().Observe(1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		var sb strings.Builder
+
+		require.NoError(t, registry.WriteText(&sb))
+		require.Contains(t, sb.String(), `total{label="value"} 1000`)
+		require.Contains(t, sb.String(), `seconds_count 1000`)
+		require.Contains(t, sb.String(), `seconds_bucket{le="0.5"} 0`)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		registry := NewRegistry()
+
+		registry.Counter("total", "", "a")
+
+		require.Panics(t, func() { registry.Gauge("total", "", "a") })
+		require.Panics(t, func() { registry.Counter("total", "", "b") })
+		require.Panics(t, func() { registry.Counter("total", "", "a").With() })
+
+		registry.Histogram("latency", "", []float64{0.1, 1}, "a")
+
+		require.NotPanics(t, func() { registry.Histogram("latency", "", []float64{1, 0.1}, "a") })
+		require.Panics(t, func() { registry.Histogram("latency", "", []float64{0.1, 1, 10}, "a") })
+		require.Panics(t, func() { registry.Histogram("latency", "", nil, "a") })
+
+		registry.Histogram("duration", "", nil)
+
+		require.NotPanics(t, func() { registry.Histogram("duration", "", DefaultBuckets) })
+	})
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TextContentType is the content type of the Prometheus text exposition format written by WriteText.
+const TextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)            // nolint: gochecknoglobals
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`) // nolint: gochecknoglobals
+)
+
+// WriteText writes all metrics in the Prometheus text exposition format, sorted by name and labels.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+
+	r.mu.RUnlock()
+
+	slices.SortFunc(families, func(a, b *family) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		f.writeText(bw)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("metrics: write: %w", err)
+	}
+
+	return nil
+}
+
+func (f *family) writeText(w *bufio.Writer) {
+	f.mu.RLock()
+
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+
+	f.mu.RUnlock()
+
+	if len(all) == 0 {
+		return
+	}
+
+	slices.SortFunc(all, func(a, b *series) int {
+		return slices.Compare(a.labels, b.labels)
+	})
+
+	if f.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	for _, s := range all {
+		if f.kind != KindHistogram {
+			writeSample(w, f.name, f.labels, s.labels, "", "", s.value.Load())
+
+			continue
+		}
+
+		s.mu.Lock()
+		counts := slices.Clone(s.counts)
+		count, sum := s.count, s.value.Load()
+		s.mu.Unlock()
+
+		var cumulative uint64
+
+		for i, bound := range f.buckets {
+			cumulative += counts[i]
+			writeSample(w, f.name+"_bucket", f.labels, s.labels, "le", formatFloat(bound), float64(cumulative))
+		}
+
+		writeSample(w, f.name+"_bucket", f.labels, s.labels, "le", "+Inf", float64(count))
+		writeSample(w, f.name+"_sum", f.labels, s.labels, "", "", sum)
+		writeSample(w, f.name+"_count", f.labels, s.labels, "", "", float64(count))
+	}
+}
+
+// writeSample writes a line of the sample, with 'extraName' label appended if it is not empty.
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			fmt.Fprintf(w, `%s="%s"`, label, labelEscaper.Replace(values[i]))
+		}
+
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+
+			fmt.Fprintf(w, `%s="%s"`, extraName, extraValue)
+		}
+
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
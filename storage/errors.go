@@ -6,4 +6,7 @@ const (
 	ErrRecordNotFound                = wherr.Error("record not found")
 	ErrUniqueConstraintViolation     = wherr.Error("unique constraint violation")
 	ErrForeignKeyConstraintViolation = wherr.Error("foreign key constraint violation")
+	ErrStaleRecord                   = wherr.Error("stale record")
+	ErrInvalidQuery                  = wherr.Error("invalid query")
+	ErrQueryCanceled                 = wherr.Error("query canceled")
 )
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+// normalize converts value to one of: nil, int64, uint64, float64, string, bool, []byte, time.Time,
+// mimicking what would be stored in the database.
+func normalize(v any) any {
+	if valuer, ok := v.(driver.Valuer); ok {
+		value := reflect.ValueOf(v)
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return nil
+		}
+
+		dv, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+
+		v = dv
+	}
+
+	value := reflect.ValueOf(v)
+
+	switch value.Kind() { // nolint: exhaustive
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		return normalize(value.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	}
+
+	return v
+}
+
+// compare returns the order of normalized values, 'ok' is false if they are not comparable,
+// which includes NULLs, same as in SQL.
+func compare(a, b any) (int, bool) { // nolint: cyclop
+	a, b = normalize(a), normalize(b)
+
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return cmp.Compare(a, b), true
+		case uint64:
+			return cmp.Compare(float64(a), float64(b)), true
+		case float64:
+			return cmp.Compare(float64(a), b), true
+		}
+	case uint64:
+		switch b := b.(type) {
+		case uint64:
+			return cmp.Compare(a, b), true
+		case int64:
+			return cmp.Compare(float64(a), float64(b)), true
+		case float64:
+			return cmp.Compare(float64(a), b), true
+		}
+	case float64:
+		switch b := b.(type) {
+		case float64:
+			return cmp.Compare(a, b), true
+		case int64:
+			return cmp.Compare(a, float64(b)), true
+		case uint64:
+			return cmp.Compare(a, float64(b)), true
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case []byte:
+		if b, ok := b.([]byte); ok {
+			return bytes.Compare(a, b), true
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), true
+		}
+	}
+
+	return 0, false
+}
+
+func equal(a, b any) bool {
+	c, ok := compare(a, b)
+
+	return ok && c == 0
+}
+
+func match(value any, filter storage.Filter) (bool, error) {
+	switch filter.Op {
+	case storage.OpIsNull:
+		return normalize(value) == nil, nil
+	case storage.OpNotNull:
+		return normalize(value) != nil, nil
+	case storage.OpIn, storage.OpNotIn:
+		values := reflect.ValueOf(filter.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return false, fmt.Errorf("%w: expected slice, got %T", storage.ErrInvalidQuery, filter.Value)
+		}
+
+		if normalize(value) == nil {
+			return false, nil
+		}
+
+		for i := range values.Len() {
+			if equal(value, values.Index(i).Interface()) {
+				return filter.Op == storage.OpIn, nil
+			}
+		}
+
+		return filter.Op == storage.OpNotIn, nil
+	case storage.OpLike:
+		pattern, ok := filter.Value.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: LIKE pattern must be string, got %T", storage.ErrInvalidQuery, filter.Value)
+		}
+
+		str, ok := normalize(value).(string)
+
+		return ok && likeRegexp(pattern).MatchString(str), nil
+	}
+
+	c, ok := compare(value, filter.Value)
+	if !ok {
+		return false, nil
+	}
+
+	switch filter.Op { // nolint: exhaustive
+	case storage.OpEq:
+		return c == 0, nil
+	case storage.OpNotEq:
+		return c != 0, nil
+	case storage.OpLt:
+		return c < 0, nil
+	case storage.OpLte:
+		return c <= 0, nil
+	case storage.OpGt:
+		return c > 0, nil
+	case storage.OpGte:
+		return c >= 0, nil
+	}
+
+	return false, fmt.Errorf("%w: unknown operator %q", storage.ErrInvalidQuery, filter.Op)
+}
+
+func likeRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+
+	sb.WriteString("^")
+
+	escaped := false
+
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		case c == '\\':
+			escaped = true
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.MustCompile("(?s)" + sb.String())
+}
+
+// sortCompare orders values like postgres does: NULLs are larger than any other value.
+func sortCompare(a, b any) int {
+	a, b = normalize(a), normalize(b)
+
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	c, _ := compare(a, b)
+
+	return c
+}
@@ -0,0 +1,539 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+var (
+	_ storage.Repository[struct{ ID int }] = (*Repository[struct{ ID int }])(nil)
+
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+)
+
+type RepositoryOpt func(*repositoryConfig)
+
+type repositoryConfig struct {
+	uniques         [][]string
+	foreignKeys     []foreignKeyConfig
+	conflictColumns []string
+}
+
+type foreignKeyConfig struct {
+	column    string
+	refModel  any
+	refColumn string
+}
+
+// NewRepository creates repository for the model 'T' in the store. Unique columns are taken
+// from gorm tags ('unique', 'uniqueIndex') and Unique options, foreign keys must be declared
+// with ForeignKey options. Stored values are shallow copies of the entities.
+func NewRepository[T any](store *Store, opts ...RepositoryOpt) (*Repository[T], error) {
+	var cfg repositoryConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	t, err := store.register(new(T), &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conflict, err := lookUpFields(t.schema, cfg.conflictColumns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conflict) == 0 {
+		conflict = []*schema.Field{t.pk}
+	}
+
+	return &Repository[T]{store: store, table: t, conflict: conflict}, nil
+}
+
+type Repository[T any] struct {
+	store    *Store
+	table    *table
+	conflict []*schema.Field
+}
+
+func (r *Repository[T]) Get(ctx context.Context, id any, opts ...storage.QueryOption) (*T, error) {
+	query := storage.NewQuery(opts...)
+	query.Filters = append(query.Filters, storage.Eq(r.table.pk.DBName, id))
+	query.Limit = 1
+
+	items, err := r.list(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &items[0], nil
+}
+
+func (r *Repository[T]) List(ctx context.Context, opts ...storage.QueryOption) ([]T, error) {
+	query := storage.NewQuery(opts...)
+
+	return r.list(ctx, &query)
+}
+
+func (r *Repository[T]) Page(ctx context.Context, opts ...storage.QueryOption) (storage.Page[T], error) {
+	query := storage.NewQuery(opts...)
+	query.SortedBy(r.table.pk.DBName)
+
+	limit := query.Limit
+	if limit > 0 {
+		query.Limit++
+	}
+
+	items, err := r.list(ctx, &query)
+	if err != nil {
+		return storage.Page[T]{}, err
+	}
+
+	if limit == 0 || len(items) <= limit {
+		return storage.Page[T]{Items: items}, nil
+	}
+
+	items = items[:limit]
+	last := reflect.ValueOf(&items[limit-1]).Elem()
+	next := make([]any, 0, len(query.Sort))
+
+	for _, s := range query.Sort {
+		fields, err := r.fields(s.Field)
+		if err != nil {
+			return storage.Page[T]{}, err
+		}
+
+		v, _ := fields[0].ValueOf(ctx, last)
+		next = append(next, v)
+	}
+
+	return storage.Page[T]{Items: items, Next: next}, nil
+}
+
+func (r *Repository[T]) Count(ctx context.Context, opts ...storage.QueryOption) (int64, error) {
+	query := storage.NewQuery(opts...)
+	query.Sort, query.Limit, query.Offset, query.After = nil, 0, 0, nil
+
+	items, err := r.list(ctx, &query)
+
+	return int64(len(items)), err
+}
+
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.create(ctx, entity)
+}
+
+// Update replaces the stored entity, keeping its creation timestamps.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	pk, existing, ok := r.table.lookup(r.primaryKey(ctx, reflect.ValueOf(entity).Elem()))
+
+	if ok && r.deleted(ctx, existing) {
+		ok = false
+	}
+
+	if !ok {
+		if _, versioned := any(entity).(storage.VersionedRecord); versioned {
+			return storage.ErrStaleRecord
+		}
+
+		return storage.ErrRecordNotFound
+	}
+
+	return r.update(ctx, entity, pk, existing)
+}
+
+// Upsert creates 'entity' or replaces the one with the same primary key (or configured columns).
+// Versioned entity replaces only the row with the same version.
+func (r *Repository[T]) Upsert(ctx context.Context, entity *T) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var (
+		value  = reflect.ValueOf(entity).Elem()
+		values = make([]any, len(r.conflict))
+	)
+
+	for i, field := range r.conflict {
+		values[i], _ = field.ValueOf(ctx, value)
+	}
+
+	for _, pk := range r.table.order {
+		if existing := r.table.rows[pk]; r.table.matches(ctx, existing, r.conflict, values) {
+			entityPK, _ := r.table.pk.ValueOf(ctx, value)
+			pkValue, _ := r.table.pk.ValueOf(ctx, existing)
+
+			if err := r.table.pk.Set(ctx, value, pkValue); err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			if err := r.update(ctx, entity, pk, existing); err != nil {
+				_ = r.table.pk.Set(ctx, value, entityPK)
+
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	// same as the database, inserted version is the next one
+	if versioned, ok := any(entity).(storage.VersionedRecord); ok {
+		current := versioned.CurrentVersion()
+		versioned.SetVersion(current + 1)
+
+		if err := r.create(ctx, entity); err != nil {
+			versioned.SetVersion(current)
+
+			return err
+		}
+
+		return nil
+	}
+
+	return r.create(ctx, entity)
+}
+
+// Delete removes the entity, soft deleting it when the model supports that.
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	pk, row, ok := r.table.lookup(id)
+	if !ok || r.deleted(ctx, row) {
+		return storage.ErrRecordNotFound
+	}
+
+	r.store.track(ctx, r.table, pk)
+
+	if field := r.softDeleteField(); field != nil {
+		return field.Set(ctx, row, gorm.DeletedAt{Time: time.Now(), Valid: true}) // nolint: wrapcheck
+	}
+
+	if err := r.store.checkReferences(ctx, r.table, row); err != nil {
+		return err
+	}
+
+	r.table.remove(pk)
+
+	return nil
+}
+
+func (r *Repository[T]) create(ctx context.Context, entity *T) error {
+	row := copyValue(reflect.ValueOf(entity).Elem())
+
+	if err := r.generatePrimaryKey(ctx, row); err != nil {
+		return err
+	}
+
+	r.setTimestamps(ctx, row, true)
+
+	if versioned, ok := row.Addr().Interface().(storage.VersionedRecord); ok && versioned.CurrentVersion() == 0 {
+		versioned.SetVersion(1)
+	}
+
+	if err := r.store.checkConstraints(ctx, r.table, row, nil); err != nil {
+		return err
+	}
+
+	pk := r.primaryKey(ctx, row)
+
+	r.store.track(ctx, r.table, pk)
+	r.table.insert(pk, row)
+	reflect.ValueOf(entity).Elem().Set(row)
+
+	return nil
+}
+
+// update replaces 'existing' row stored with primary key 'pk', checking the version of versioned entity.
+func (r *Repository[T]) update(ctx context.Context, entity *T, pk any, existing reflect.Value) error {
+	var (
+		value        = reflect.ValueOf(entity).Elem()
+		versioned, _ = any(entity).(storage.VersionedRecord)
+	)
+
+	if versioned != nil {
+		stored, _ := existing.Addr().Interface().(storage.VersionedRecord)
+		if stored.CurrentVersion() != versioned.CurrentVersion() {
+			return storage.ErrStaleRecord
+		}
+	}
+
+	row := copyValue(value)
+	r.keepCreateTime(ctx, row, existing)
+	r.setTimestamps(ctx, row, false)
+
+	if versioned != nil {
+		rowVersion, _ := row.Addr().Interface().(storage.VersionedRecord)
+		rowVersion.SetVersion(versioned.CurrentVersion() + 1)
+	}
+
+	if err := r.store.checkConstraints(ctx, r.table, row, pk); err != nil {
+		return err
+	}
+
+	r.store.track(ctx, r.table, pk)
+	r.table.insert(pk, row)
+
+	// entity keeps its own creation time, same as after the database update
+	r.keepCreateTime(ctx, row, value)
+	value.Set(row)
+
+	return nil
+}
+
+func (r *Repository[T]) list(ctx context.Context, query *storage.Query) ([]T, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rows := make([]reflect.Value, 0, len(r.table.order))
+
+	for _, pk := range r.table.order {
+		row := r.table.rows[pk]
+
+		if !query.WithDeleted && r.deleted(ctx, row) {
+			continue
+		}
+
+		ok, err := r.match(ctx, row, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+
+	if err := r.sort(ctx, rows, query.Sort); err != nil {
+		return nil, err
+	}
+
+	rows = rows[min(query.Offset, len(rows)):]
+
+	if query.Limit > 0 {
+		rows = rows[:min(query.Limit, len(rows))]
+	}
+
+	items := make([]T, 0, len(rows))
+
+	for _, row := range rows {
+		items = append(items, row.Interface().(T)) // nolint: forcetypeassert
+	}
+
+	return items, nil
+}
+
+func (r *Repository[T]) match(ctx context.Context, row reflect.Value, query *storage.Query) (bool, error) {
+	for _, filter := range query.Filters {
+		ok, err := r.matchFilter(ctx, row, filter)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	if len(query.After) == 0 {
+		return true, nil
+	}
+
+	// keyset: (a > x) OR (a = x AND b > y) OR ...
+	for i, s := range query.Sort {
+		ok, err := r.matchFilter(ctx, row, keysetFilter(s, query.After[i]))
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			return true, nil
+		}
+
+		ok, err = r.matchFilter(ctx, row, storage.Eq(s.Field, query.After[i]))
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+func (r *Repository[T]) matchFilter(ctx context.Context, row reflect.Value, filter storage.Filter) (bool, error) {
+	fields, err := r.fields(filter.Field)
+	if err != nil {
+		return false, err
+	}
+
+	value, _ := fields[0].ValueOf(ctx, row)
+
+	return match(value, filter)
+}
+
+func (r *Repository[T]) sort(ctx context.Context, rows []reflect.Value, sort []storage.Sort) error {
+	fields := make([]*schema.Field, 0, len(sort))
+
+	for _, s := range sort {
+		f, err := r.fields(s.Field)
+		if err != nil {
+			return err
+		}
+
+		fields = append(fields, f[0])
+	}
+
+	slices.SortStableFunc(rows, func(a, b reflect.Value) int {
+		for i, field := range fields {
+			av, _ := field.ValueOf(ctx, a)
+			bv, _ := field.ValueOf(ctx, b)
+
+			c := sortCompare(av, bv)
+			if sort[i].Desc {
+				c = -c
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	})
+
+	return nil
+}
+
+func (r *Repository[T]) fields(columns ...string) ([]*schema.Field, error) {
+	fields, err := lookUpFields(r.table.schema, columns...)
+	if err != nil {
+		return nil, storage.ErrInvalidQuery
+	}
+
+	return fields, nil
+}
+
+func (r *Repository[T]) primaryKey(ctx context.Context, row reflect.Value) any {
+	pk, _ := r.table.pk.ValueOf(ctx, row)
+
+	return normalize(pk)
+}
+
+// generatePrimaryKey fills zero integer keys with sequence and zero uuid keys with random uuid.
+func (r *Repository[T]) generatePrimaryKey(ctx context.Context, row reflect.Value) error {
+	if _, zero := r.table.pk.ValueOf(ctx, row); !zero {
+		return nil
+	}
+
+	switch {
+	case r.table.pk.FieldType == uuidType:
+		return r.table.pk.Set(ctx, row, uuid.New()) // nolint: wrapcheck
+	case r.table.pk.DataType == schema.Int || r.table.pk.DataType == schema.Uint:
+		r.table.nextID++
+
+		return r.table.pk.Set(ctx, row, r.table.nextID) // nolint: wrapcheck
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) setTimestamps(ctx context.Context, row reflect.Value, create bool) {
+	now := time.Now()
+
+	for _, field := range r.table.schema.Fields {
+		if create && field.AutoCreateTime > 0 {
+			if _, zero := field.ValueOf(ctx, row); zero {
+				_ = field.Set(ctx, row, now)
+			}
+		}
+
+		if field.AutoUpdateTime > 0 {
+			if _, zero := field.ValueOf(ctx, row); zero || !create {
+				_ = field.Set(ctx, row, now)
+			}
+		}
+	}
+}
+
+func (r *Repository[T]) keepCreateTime(ctx context.Context, row, from reflect.Value) {
+	for _, field := range r.table.schema.Fields {
+		if field.AutoCreateTime > 0 {
+			v, _ := field.ValueOf(ctx, from)
+			_ = field.Set(ctx, row, v)
+		}
+	}
+}
+
+func (r *Repository[T]) softDeleteField() *schema.Field {
+	for _, field := range r.table.schema.Fields {
+		if field.FieldType == deletedAtType {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) deleted(ctx context.Context, row reflect.Value) bool {
+	field := r.softDeleteField()
+	if field == nil {
+		return false
+	}
+
+	v, _ := field.ValueOf(ctx, row)
+
+	return normalize(v) != nil
+}
+
+func keysetFilter(s storage.Sort, value any) storage.Filter {
+	if s.Desc {
+		return storage.Lt(s.Field, value)
+	}
+
+	return storage.Gt(s.Field, value)
+}
+
+// Unique declares unique constraint on the columns.
+func Unique(columns ...string) RepositoryOpt {
+	return func(cfg *repositoryConfig) {
+		cfg.uniques = append(cfg.uniques, columns)
+	}
+}
+
+// ForeignKey declares that 'column' references 'refColumn' of the model 'Ref'.
+// Referenced rows can't be hard deleted while referenced.
+func ForeignKey[Ref any](column, refColumn string) RepositoryOpt {
+	return func(cfg *repositoryConfig) {
+		cfg.foreignKeys = append(cfg.foreignKeys, foreignKeyConfig{
+			column:    column,
+			refModel:  new(Ref),
+			refColumn: refColumn,
+		})
+	}
+}
+
+func WithConflictColumns(columns ...string) RepositoryOpt {
+	return func(cfg *repositoryConfig) {
+		cfg.conflictColumns = columns
+	}
+}
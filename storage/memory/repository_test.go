@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+type testUser struct {
+	ID        int64
+	Name      string
+	Email     string `gorm:"uniqueIndex"`
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+type testPost struct {
+	ID     int64
+	UserID int64
+	Title  string
+
+	storage.Versioned
+}
+
+func newRepos(t *testing.T) (*Store, *Repository[testUser], *Repository[testPost]) {
+	t.Helper()
+
+	store := NewStore()
+
+	users, err := NewRepository[testUser](store)
+	require.NoError(t, err)
+
+	posts, err := NewRepository[testPost](store, ForeignKey[testUser]("user_id", "id"))
+	require.NoError(t, err)
+
+	return store, users, posts
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	_, users, _ := newRepos(t)
+
+	bob := testUser{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, users.Create(ctx, &bob))
+	require.EqualValues(t, 1, bob.ID)
+	require.False(t, bob.CreatedAt.IsZero())
+
+	got, err := users.Get(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Equal(t, bob, *got)
+
+	err = users.Create(ctx, &testUser{Name: "bob2", Email: "bob@example.com"})
+	require.ErrorIs(t, err, storage.ErrUniqueConstraintViolation)
+
+	bob.Name = "robert"
+	require.NoError(t, users.Update(ctx, &bob))
+
+	got, err = users.Get(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Equal(t, "robert", got.Name)
+
+	require.ErrorIs(t, users.Update(ctx, &testUser{ID: 42}), storage.ErrRecordNotFound)
+
+	require.NoError(t, users.Delete(ctx, bob.ID))
+	require.ErrorIs(t, users.Delete(ctx, bob.ID), storage.ErrRecordNotFound)
+
+	_, err = users.Get(ctx, bob.ID)
+	require.ErrorIs(t, err, storage.ErrRecordNotFound)
+
+	got, err = users.Get(ctx, bob.ID, storage.WithDeleted())
+	require.NoError(t, err)
+	require.True(t, got.DeletedAt.Valid)
+}
+
+func TestRepositoryQuery(t *testing.T) {
+	ctx := context.Background()
+	_, users, _ := newRepos(t)
+
+	for _, name := range []string{"carol", "alice", "dave", "bob"} {
+		require.NoError(t, users.Create(ctx, &testUser{Name: name, Email: name + "@example.com"}))
+	}
+
+	names := func(items []testUser) []string {
+		res := make([]string, 0, len(items))
+		for _, item := range items {
+			res = append(res, item.Name)
+		}
+
+		return res
+	}
+
+	items, err := users.List(ctx,
+		storage.Where(storage.In("name", []string{"alice", "bob", "dave"})),
+		storage.OrderByDesc("name"),
+		storage.Limit(2),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dave", "bob"}, names(items))
+
+	count, err := users.Count(ctx, storage.Where(storage.Like("email", "%a%@%")))
+	require.NoError(t, err)
+	require.EqualValues(t, 3, count)
+
+	page, err := users.Page(ctx, storage.OrderBy("name"), storage.Limit(3))
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob", "carol"}, names(page.Items))
+	require.Equal(t, []any{"carol", int64(1)}, page.Next)
+
+	page, err = users.Page(ctx, storage.OrderBy("name"), storage.Limit(3), storage.After(page.Next...))
+	require.NoError(t, err)
+	require.Equal(t, []string{"dave"}, names(page.Items))
+	require.Nil(t, page.Next)
+
+	_, err = users.List(ctx, storage.Where(storage.Eq("unknown", 1)))
+	require.ErrorIs(t, err, storage.ErrInvalidQuery)
+}
+
+func TestRepositoryConstraints(t *testing.T) {
+	ctx := context.Background()
+	store, users, posts := newRepos(t)
+
+	err := posts.Create(ctx, &testPost{UserID: 1, Title: "orphan"})
+	require.ErrorIs(t, err, storage.ErrForeignKeyConstraintViolation)
+
+	bob := testUser{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, users.Create(ctx, &bob))
+
+	post := testPost{UserID: bob.ID, Title: "hello"}
+	require.NoError(t, posts.Create(ctx, &post))
+	require.EqualValues(t, 1, post.Version)
+
+	stale := post
+
+	post.Title = "hello, world"
+	require.NoError(t, posts.Update(ctx, &post))
+	require.EqualValues(t, 2, post.Version)
+	require.ErrorIs(t, posts.Update(ctx, &stale), storage.ErrStaleRecord)
+
+	errRollback := errors.New("rollback")
+
+	err = store.Transaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, posts.Delete(ctx, post.ID))
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	got, err := posts.Get(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post, *got)
+}
+
+func TestRepositoryUpsertVersioned(t *testing.T) {
+	ctx := context.Background()
+	_, users, posts := newRepos(t)
+
+	bob := testUser{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, users.Create(ctx, &bob))
+
+	post := testPost{UserID: bob.ID, Title: "hello"}
+	require.NoError(t, posts.Upsert(ctx, &post))
+	require.EqualValues(t, 1, post.Version)
+
+	stale := post
+
+	post.Title = "hello, world"
+	require.NoError(t, posts.Update(ctx, &post))
+	require.EqualValues(t, 2, post.Version)
+
+	stale.Title = "stale"
+	require.ErrorIs(t, posts.Upsert(ctx, &stale), storage.ErrStaleRecord)
+	require.EqualValues(t, 1, stale.Version)
+
+	got, err := posts.Get(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post, *got)
+
+	post.Title = "upserted"
+	require.NoError(t, posts.Upsert(ctx, &post))
+	require.EqualValues(t, 3, post.Version)
+
+	got, err = posts.Get(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post, *got)
+}
+
+func TestStoreTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	store, users, _ := newRepos(t)
+
+	bob := testUser{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, users.Create(ctx, &bob))
+
+	errRollback := errors.New("rollback")
+
+	err := store.Transaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, users.Create(txCtx, &testUser{Name: "alice", Email: "alice@example.com"}))
+		require.NoError(t, users.Update(txCtx, &testUser{ID: bob.ID, Name: "robert", Email: bob.Email}))
+
+		// nested transaction changes are reverted with the outer one
+		require.NoError(t, store.Transaction(txCtx, func(txCtx context.Context) error {
+			return users.Delete(txCtx, bob.ID)
+		}))
+
+		// written outside of the transaction
+		require.NoError(t, users.Create(ctx, &testUser{Name: "carol", Email: "carol@example.com"}))
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	items, err := users.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, bob, items[0])
+	require.Equal(t, "carol", items[1].Name)
+}
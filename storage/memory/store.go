@@ -0,0 +1,401 @@
+// Package memory provides in-memory storage fakes for tests of code built on
+// the storage.Repository and storage.Transactor interfaces.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+var _ storage.Transactor = (*Store)(nil)
+
+type txKey struct{}
+
+func NewStore() *Store {
+	return &Store{
+		tables: make(map[string]*table),
+		namer:  schema.NamingStrategy{},
+	}
+}
+
+// Store holds tables of all repositories created with it, so foreign keys
+// between them are checked. Models are mapped to tables the same way gorm does.
+type Store struct {
+	mu      sync.Mutex
+	tables  map[string]*table
+	txMu    sync.Mutex
+	schemas sync.Map
+	namer   schema.Namer
+}
+
+// Transaction runs 'fn' and reverts rows it changed if it fails.
+// Transactions are serialized, but not isolated from writes made outside of them.
+func (s *Store) Transaction(ctx context.Context, fn func(ctx context.Context) error) (err error) { // nolint: nonamedreturns
+	parent, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok || parent.store != s {
+		s.txMu.Lock()
+		defer s.txMu.Unlock()
+
+		parent = nil
+	}
+
+	tx := &transaction{store: s, changes: make(map[rowKey]change)}
+	ctx = context.WithValue(ctx, txKey{}, tx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.rollback(tx)
+
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		s.rollback(tx)
+
+		return err
+	}
+
+	if parent != nil {
+		s.mu.Lock()
+		parent.merge(tx)
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (s *Store) parse(model any) (*schema.Schema, error) {
+	sch, err := schema.Parse(model, &s.schemas, s.namer)
+	if err != nil {
+		return nil, fmt.Errorf("memory: parse model: %w", err)
+	}
+
+	return sch, nil
+}
+
+func (s *Store) register(model any, cfg *repositoryConfig) (*table, error) {
+	sch, err := s.parse(model)
+	if err != nil {
+		return nil, err
+	}
+
+	if sch.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("memory: model %s has no primary key", sch.Name) // nolint: err113
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tables[sch.Table]
+	if !ok {
+		t = newTable(sch)
+		s.tables[sch.Table] = t
+	}
+
+	for _, columns := range cfg.uniques {
+		fields, err := lookUpFields(sch, columns...)
+		if err != nil {
+			return nil, err
+		}
+
+		t.uniques = append(t.uniques, fields)
+	}
+
+	for _, fk := range cfg.foreignKeys {
+		fields, err := lookUpFields(sch, fk.column)
+		if err != nil {
+			return nil, err
+		}
+
+		refSchema, err := s.parse(fk.refModel)
+		if err != nil {
+			return nil, err
+		}
+
+		refFields, err := lookUpFields(refSchema, fk.refColumn)
+		if err != nil {
+			return nil, err
+		}
+
+		t.foreignKeys = append(t.foreignKeys, foreignKey{
+			field:     fields[0],
+			refTable:  refSchema.Table,
+			refColumn: refFields[0].DBName,
+		})
+	}
+
+	return t, nil
+}
+
+// checkConstraints verifies unique and foreign keys of the row, ignoring the row with primary key 'self'.
+func (s *Store) checkConstraints(ctx context.Context, t *table, row reflect.Value, self any) error {
+	for _, fields := range t.allUniques() {
+		values := make([]any, len(fields))
+
+		for i, field := range fields {
+			values[i], _ = field.ValueOf(ctx, row)
+		}
+
+		for pk, other := range t.rows {
+			if self != nil && equal(pk, self) {
+				continue
+			}
+
+			if t.matches(ctx, other, fields, values) {
+				return storage.ErrUniqueConstraintViolation
+			}
+		}
+	}
+
+	for _, fk := range t.foreignKeys {
+		value, _ := fk.field.ValueOf(ctx, row)
+		if normalize(value) == nil {
+			continue
+		}
+
+		ref, ok := s.tables[fk.refTable]
+		if !ok || !ref.contains(ctx, fk.refColumn, value) {
+			return storage.ErrForeignKeyConstraintViolation
+		}
+	}
+
+	return nil
+}
+
+// checkReferences verifies no rows of other tables reference the row being deleted.
+func (s *Store) checkReferences(ctx context.Context, t *table, row reflect.Value) error {
+	for _, other := range s.tables {
+		for _, fk := range other.foreignKeys {
+			if fk.refTable != t.schema.Table {
+				continue
+			}
+
+			value, _ := t.schema.FieldsByDBName[fk.refColumn].ValueOf(ctx, row)
+
+			if other.contains(ctx, fk.field.DBName, value) {
+				return storage.ErrForeignKeyConstraintViolation
+			}
+		}
+	}
+
+	return nil
+}
+
+type rowKey struct {
+	table *table
+	pk    any
+}
+
+// change is the state of the row before the transaction changed it.
+type change struct {
+	row   reflect.Value
+	index int
+}
+
+type transaction struct {
+	store   *Store
+	changes map[rowKey]change
+}
+
+// track remembers the row with primary key 'pk' before the first change made to it in the
+// transaction of 'ctx'. Must be called with the store lock held.
+func (s *Store) track(ctx context.Context, t *table, pk any) {
+	tx, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok || tx.store != s {
+		return
+	}
+
+	key := rowKey{table: t, pk: pk}
+	if _, ok := tx.changes[key]; ok {
+		return
+	}
+
+	c := change{index: slices.Index(t.order, pk)}
+	if row, ok := t.rows[pk]; ok {
+		c.row = copyValue(row)
+	}
+
+	tx.changes[key] = c
+}
+
+// rollback reverts rows changed in the transaction, generated ids are not reused same as sequences.
+func (s *Store) rollback(tx *transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := slices.Collect(maps.Keys(tx.changes))
+	slices.SortFunc(keys, func(a, b rowKey) int {
+		return cmp.Compare(tx.changes[a].index, tx.changes[b].index)
+	})
+
+	for _, key := range keys {
+		c := tx.changes[key]
+
+		if !c.row.IsValid() {
+			key.table.remove(key.pk)
+
+			continue
+		}
+
+		if _, ok := key.table.rows[key.pk]; !ok {
+			index := min(c.index, len(key.table.order))
+			key.table.order = slices.Insert(key.table.order, index, key.pk)
+		}
+
+		key.table.rows[key.pk] = c.row
+	}
+}
+
+// merge keeps changes of the nested transaction 'tx' to revert them with the parent.
+func (p *transaction) merge(tx *transaction) {
+	for key, c := range tx.changes {
+		if _, ok := p.changes[key]; !ok {
+			p.changes[key] = c
+		}
+	}
+}
+
+type foreignKey struct {
+	field     *schema.Field
+	refTable  string
+	refColumn string
+}
+
+type tableData struct {
+	rows   map[any]reflect.Value
+	order  []any
+	nextID int64
+}
+
+func newTableData() tableData {
+	return tableData{rows: make(map[any]reflect.Value)}
+}
+
+func newTable(sch *schema.Schema) *table {
+	return &table{
+		schema:    sch,
+		pk:        sch.PrioritizedPrimaryField,
+		tableData: newTableData(),
+	}
+}
+
+type table struct {
+	schema      *schema.Schema
+	pk          *schema.Field
+	uniques     [][]*schema.Field
+	foreignKeys []foreignKey
+
+	tableData
+}
+
+// allUniques returns primary key, declared unique columns and the ones from gorm tags.
+func (t *table) allUniques() [][]*schema.Field {
+	uniques := [][]*schema.Field{{t.pk}}
+
+	for _, field := range t.schema.Fields {
+		if field.Unique && !field.PrimaryKey {
+			uniques = append(uniques, []*schema.Field{field})
+		}
+	}
+
+	for _, index := range t.schema.ParseIndexes() {
+		if index.Class != "UNIQUE" || index.Where != "" {
+			continue
+		}
+
+		fields := make([]*schema.Field, 0, len(index.Fields))
+		for _, opt := range index.Fields {
+			fields = append(fields, opt.Field)
+		}
+
+		uniques = append(uniques, fields)
+	}
+
+	return append(uniques, t.uniques...)
+}
+
+// matches reports whether row has all 'values' in 'fields', NULLs never match.
+func (t *table) matches(ctx context.Context, row reflect.Value, fields []*schema.Field, values []any) bool {
+	for i, field := range fields {
+		v, _ := field.ValueOf(ctx, row)
+
+		if !equal(v, values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *table) contains(ctx context.Context, column string, value any) bool {
+	field := t.schema.FieldsByDBName[column]
+
+	for _, row := range t.rows {
+		if t.matches(ctx, row, []*schema.Field{field}, []any{value}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup finds the row by primary key of any comparable type, e.g. int for uint keys.
+func (t *table) lookup(id any) (any, reflect.Value, bool) {
+	if row, ok := t.rows[normalize(id)]; ok {
+		return normalize(id), row, true
+	}
+
+	for _, pk := range t.order {
+		if equal(pk, id) {
+			return pk, t.rows[pk], true
+		}
+	}
+
+	return nil, reflect.Value{}, false
+}
+
+func (t *table) insert(pk any, row reflect.Value) {
+	if _, ok := t.rows[pk]; !ok {
+		t.order = append(t.order, pk)
+	}
+
+	t.rows[pk] = row
+}
+
+func (t *table) remove(pk any) {
+	delete(t.rows, pk)
+
+	t.order = slices.DeleteFunc(t.order, func(v any) bool { return v == pk })
+}
+
+func lookUpFields(sch *schema.Schema, columns ...string) ([]*schema.Field, error) {
+	fields := make([]*schema.Field, 0, len(columns))
+
+	for _, column := range columns {
+		field := sch.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("memory: model %s has no field %q", sch.Name, column) // nolint: err113
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+
+	return c
+}
@@ -0,0 +1,395 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+const (
+	auditPluginName    = "wheel:audit"
+	auditBeforeKey     = auditPluginName + ":before"
+	defaultAuditTable  = "audit_log"
+	commitCallbackName = "gorm:commit_or_rollback_transaction"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+var auditIndexes = []tableIndex{ // nolint: gochecknoglobals
+	{suffix: "entity", definition: "(entity_type, entity_id)"},
+}
+
+type AuditEntry struct {
+	ID            int64                  `gorm:"primaryKey"`
+	EntityType    string                 `gorm:"not null"`
+	EntityID      string                 `gorm:"not null"`
+	Action        AuditAction            `gorm:"not null"`
+	Principal     string                 `gorm:"not null;default:''"`
+	TransactionID uuid.UUID              `gorm:"type:uuid"`
+	Changes       map[string]AuditChange `gorm:"type:jsonb;serializer:json"`
+	CreatedAt     time.Time              `gorm:"not null"`
+}
+
+type AuditOpt func(*Audit)
+
+// NewAudit records changes of the models made through 'db' into the audit table, in the
+// transaction of the change. Principal and transaction ID are taken from whctx.
+// Only changes made with gorm create, update and delete are recorded, raw SQL is not.
+func NewAudit(db *gorm.DB, models []any, opts ...AuditOpt) (*Audit, error) {
+	a := &Audit{
+		db:     db,
+		table:  defaultAuditTable,
+		models: make(map[string]struct{}, len(models)),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("postgres: audit: parse model: %w", err)
+		}
+
+		if stmt.Schema.PrioritizedPrimaryField == nil {
+			return nil, fmt.Errorf("postgres: audit: model %s has no primary key", stmt.Schema.Name) // nolint: err113
+		}
+
+		a.models[stmt.Schema.Table] = struct{}{}
+	}
+
+	if err := db.Use(a); err != nil {
+		return nil, fmt.Errorf("postgres: audit: %w", err)
+	}
+
+	return a, nil
+}
+
+type Audit struct {
+	db     *gorm.DB
+	table  string
+	models map[string]struct{}
+}
+
+func (a *Audit) Name() string {
+	return auditPluginName
+}
+
+func (a *Audit) Initialize(db *gorm.DB) error {
+	name := auditPluginName + ":record"
+
+	err := errors.Join(
+		db.Callback().Create().After("gorm:create").Before(commitCallbackName).Register(name, a.recordCreate),
+		db.Callback().Update().Before("gorm:update").Register(auditBeforeKey, a.captureBefore),
+		db.Callback().Update().After("gorm:update").Before(commitCallbackName).Register(name, a.recordUpdate),
+		db.Callback().Delete().Before("gorm:delete").Register(auditBeforeKey, a.captureBefore),
+		db.Callback().Delete().After("gorm:delete").Before(commitCallbackName).Register(name, a.recordDelete),
+	)
+	if err != nil {
+		return fmt.Errorf("register audit callbacks: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Audit) Migrate(ctx context.Context) error {
+	tx := a.db.WithContext(ctx)
+
+	if err := tx.Table(a.table).AutoMigrate(&AuditEntry{}); err != nil {
+		return fmt.Errorf("postgres: audit: migrate: %w", err)
+	}
+
+	if err := createIndexes(tx, a.table, auditIndexes...); err != nil {
+		return fmt.Errorf("postgres: audit: migrate: %w", err)
+	}
+
+	return nil
+}
+
+// History returns changes of the entity of 'model' type with primary key 'id', oldest first.
+func (a *Audit) History(ctx context.Context, model any, id any) ([]AuditEntry, error) {
+	stmt := &gorm.Statement{DB: a.db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("postgres: audit: parse model: %w", err)
+	}
+
+	var entries []AuditEntry
+
+	err := Conn(ctx, a.db).Table(a.table).
+		Where("entity_type = ? AND entity_id = ?", stmt.Schema.Table, entityID(id)).
+		Order("created_at, id").
+		Find(&entries).Error
+
+	return entries, ActualError(err)
+}
+
+func (a *Audit) audited(db *gorm.DB) bool {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return false
+	}
+
+	_, ok := a.models[db.Statement.Table]
+
+	return ok
+}
+
+func (a *Audit) recordCreate(db *gorm.DB) {
+	if !a.audited(db) {
+		return
+	}
+
+	var (
+		ctx     = db.Statement.Context
+		sch     = db.Statement.Schema
+		entries []AuditEntry
+	)
+
+	add := func(value reflect.Value) {
+		changes := make(map[string]AuditChange, len(sch.DBNames))
+
+		for _, name := range sch.DBNames {
+			v, _ := sch.FieldsByDBName[name].ValueOf(ctx, value)
+			changes[name] = AuditChange{After: v}
+		}
+
+		id, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, value)
+		entries = append(entries, a.entry(db, AuditCreate, id, changes))
+	}
+
+	switch value := db.Statement.ReflectValue; value.Kind() { // nolint: exhaustive
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			add(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		add(value)
+	}
+
+	a.write(db, entries)
+}
+
+func (a *Audit) captureBefore(db *gorm.DB) {
+	if !a.audited(db) {
+		return
+	}
+
+	conds := a.conditions(db)
+	if len(conds) == 0 && !db.AllowGlobalUpdate {
+		return
+	}
+
+	rows, err := a.rows(db, db.Statement.Unscoped, conds...)
+	if err != nil {
+		_ = db.AddError(err)
+
+		return
+	}
+
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+func (a *Audit) recordUpdate(db *gorm.DB) {
+	before, ok := a.before(db)
+	if !ok {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	ids := make([]any, 0, len(before))
+
+	for _, row := range before {
+		ids = append(ids, row[pk.DBName])
+	}
+
+	after, err := a.rows(db, true, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: ids})
+	if err != nil {
+		_ = db.AddError(err)
+
+		return
+	}
+
+	afterByID := make(map[string]map[string]any, len(after))
+	for _, row := range after {
+		afterByID[entityID(row[pk.DBName])] = row
+	}
+
+	entries := make([]AuditEntry, 0, len(before))
+
+	for _, row := range before {
+		id := row[pk.DBName]
+
+		changes := diff(row, afterByID[entityID(id)])
+		if len(changes) == 0 {
+			continue
+		}
+
+		entries = append(entries, a.entry(db, AuditUpdate, id, changes))
+	}
+
+	a.write(db, entries)
+}
+
+func (a *Audit) recordDelete(db *gorm.DB) {
+	before, ok := a.before(db)
+	if !ok {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	entries := make([]AuditEntry, 0, len(before))
+
+	for _, row := range before {
+		entries = append(entries, a.entry(db, AuditDelete, row[pk.DBName], diff(row, nil)))
+	}
+
+	a.write(db, entries)
+}
+
+func (a *Audit) before(db *gorm.DB) ([]map[string]any, bool) {
+	if !a.audited(db) || db.Statement.RowsAffected == 0 {
+		return nil, false
+	}
+
+	v, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return nil, false
+	}
+
+	rows, ok := v.([]map[string]any)
+
+	return rows, ok && len(rows) > 0
+}
+
+// conditions returns conditions of the statement, including primary keys of the model value
+// or its elements, the same gorm adds to the update and delete.
+func (a *Audit) conditions(db *gorm.DB) []clause.Expression {
+	var conds []clause.Expression
+
+	if where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where); ok {
+		conds = append(conds, where.Exprs...)
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+
+	var ids []any
+
+	for _, value := range structValues(db.Statement.ReflectValue) {
+		if id, zero := pk.ValueOf(db.Statement.Context, value); !zero {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > 0 {
+		conds = append(conds, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: ids})
+	}
+
+	return conds
+}
+
+// rows loads current state of the matching rows using the connection of the statement.
+func (a *Audit) rows(db *gorm.DB, unscoped bool, conds ...clause.Expression) ([]map[string]any, error) {
+	tx := a.session(db)
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+
+	var rows []map[string]any
+
+	err := tx.Model(reflect.New(db.Statement.Schema.ModelType).Interface()).
+		Table(db.Statement.Table).
+		Clauses(clause.Where{Exprs: conds}).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("postgres: audit: load rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+func (a *Audit) entry(db *gorm.DB, action AuditAction, id any, changes map[string]AuditChange) AuditEntry {
+	ctx := db.Statement.Context
+
+	return AuditEntry{
+		EntityType:    db.Statement.Table,
+		EntityID:      entityID(id),
+		Action:        action,
+		Principal:     whctx.Principal(ctx),
+		TransactionID: whctx.TransactionID(ctx),
+		Changes:       changes,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func (a *Audit) write(db *gorm.DB, entries []AuditEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := a.session(db).Table(a.table).Create(&entries).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("postgres: audit: write: %w", err))
+	}
+}
+
+// session returns new statement on the same connection (transaction) as 'db'.
+func (a *Audit) session(db *gorm.DB) *gorm.DB {
+	tx := db.Session(&gorm.Session{NewDB: true, Context: UsePrimary(db.Statement.Context)})
+	tx.Statement.ConnPool = db.Statement.ConnPool
+
+	return tx
+}
+
+func diff(before, after map[string]any) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+
+	for name, v := range before {
+		if after == nil || !reflect.DeepEqual(v, after[name]) {
+			changes[name] = AuditChange{Before: v, After: after[name]}
+		}
+	}
+
+	for name, v := range after {
+		if _, ok := before[name]; !ok {
+			changes[name] = AuditChange{After: v}
+		}
+	}
+
+	return changes
+}
+
+func entityID(id any) string {
+	switch id := id.(type) {
+	case [16]byte:
+		return uuid.UUID(id).String()
+	case []byte:
+		return string(id)
+	}
+
+	return fmt.Sprint(id)
+}
+
+// WithAuditTable sets the table of audit entries, "audit_log" by default.
+func WithAuditTable(table string) AuditOpt {
+	return func(a *Audit) {
+		a.table = table
+	}
+}
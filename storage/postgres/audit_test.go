@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+func TestAuditCreate(t *testing.T) {
+	db := dryRunDB(t)
+
+	_, err := NewAudit(db, []any{&testUser{}})
+	require.NoError(t, err)
+
+	var entries []AuditEntry
+
+	err = db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		if e, ok := tx.Statement.Dest.(*[]AuditEntry); ok {
+			entries = *e
+		}
+	})
+	require.NoError(t, err)
+
+	txID := uuid.New()
+	ctx := whctx.WithPrincipal(whctx.WithTransactionID(context.Background(), txID), "alice")
+
+	require.NoError(t, db.WithContext(ctx).Create(&testUser{ID: 7, Name: "bob"}).Error)
+	require.Len(t, entries, 1)
+	require.Equal(t, "test_users", entries[0].EntityType)
+	require.Equal(t, "7", entries[0].EntityID)
+	require.Equal(t, AuditCreate, entries[0].Action)
+	require.Equal(t, "alice", entries[0].Principal)
+	require.Equal(t, txID, entries[0].TransactionID)
+	require.Equal(t, AuditChange{After: "bob"}, entries[0].Changes["name"])
+
+	entries = nil
+
+	require.NoError(t, db.WithContext(ctx).Create(&testTenantUser{Name: "bob"}).Error)
+	require.Empty(t, entries)
+}
+
+func TestAuditBeforeConditions(t *testing.T) {
+	db := dryRunDB(t)
+
+	_, err := NewAudit(db, []any{&testUser{}})
+	require.NoError(t, err)
+
+	var loaded []string
+
+	err = db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		loaded = append(loaded, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Delete(&testUser{ID: 1}).Error)
+	require.NoError(t, db.Delete(&[]testUser{{ID: 1}, {ID: 2}}).Error)
+	require.NoError(t, db.Model(&[]testUser{{ID: 3}, {ID: 4}}).Update("name", "bob").Error)
+
+	require.Len(t, loaded, 3)
+	require.Contains(t, loaded[0], `WHERE "test_users"."id" = 1`)
+	require.Contains(t, loaded[1], `WHERE "test_users"."id" IN (1,2)`)
+	require.Contains(t, loaded[2], `WHERE "test_users"."id" IN (3,4)`)
+}
+
+func TestAuditDiff(t *testing.T) {
+	before := map[string]any{"id": int64(1), "name": "bob", "email": "bob@example.com"}
+	after := map[string]any{"id": int64(1), "name": "robert", "email": "bob@example.com"}
+
+	require.Equal(t, map[string]AuditChange{"name": {Before: "bob", After: "robert"}}, diff(before, after))
+	require.Len(t, diff(before, nil), 3)
+	require.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", entityID([16]byte(uuid.NameSpaceDNS)))
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+// backoff returns exponentially growing delay for the zero based attempt, capped by limit.
+func backoff(attempt int, initial, limit time.Duration) time.Duration {
+	if initial <= 0 {
+		return 0
+	}
+
+	delay := initial
+
+	for range attempt {
+		delay *= 2
+
+		if limit > 0 && delay >= limit {
+			return limit
+		}
+	}
+
+	if limit > 0 {
+		return min(delay, limit)
+	}
+
+	return delay
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
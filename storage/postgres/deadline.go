@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	ErrNoDeadline = wherr.Error("statement context has no deadline")
+
+	deadlinePluginName = "wheel:deadline"
+)
+
+type noDeadlineKey struct{}
+
+type DeadlineCheckOpt func(*DeadlineCheck)
+
+// NewDeadlineCheck creates gorm plugin failing statements whose context has no deadline
+// with ErrNoDeadline, e.g. when WithContext or Conn is not called with the request context,
+// so a query would outlive the request. Statements of background work must run
+// with WithoutDeadline context.
+//
+//	db.Use(postgres.NewDeadlineCheck(postgres.WarnWithoutDeadline(log)))
+func NewDeadlineCheck(opts ...DeadlineCheckOpt) *DeadlineCheck {
+	d := &DeadlineCheck{}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+type DeadlineCheck struct {
+	logger logger.Logger
+}
+
+func (d *DeadlineCheck) Name() string {
+	return deadlinePluginName
+}
+
+func (d *DeadlineCheck) Initialize(db *gorm.DB) error {
+	name := deadlinePluginName + ":check"
+
+	err := errors.Join(
+		db.Callback().Create().Before("gorm:create").Register(name, d.check),
+		db.Callback().Query().Before("gorm:query").Register(name, d.check),
+		db.Callback().Update().Before("gorm:update").Register(name, d.check),
+		db.Callback().Delete().Before("gorm:delete").Register(name, d.check),
+		db.Callback().Row().Before("gorm:row").Register(name, d.check),
+		db.Callback().Raw().Before("gorm:raw").Register(name, d.check),
+	)
+	if err != nil {
+		return fmt.Errorf("register deadline callbacks: %w", err)
+	}
+
+	return nil
+}
+
+// WithoutDeadline returns context in which statements are not checked, e.g. for migrations and jobs.
+func WithoutDeadline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noDeadlineKey{}, true)
+}
+
+func (d *DeadlineCheck) check(db *gorm.DB) {
+	ctx := db.Statement.Context
+
+	if _, ok := ctx.Deadline(); ok {
+		return
+	}
+
+	if skip, _ := ctx.Value(noDeadlineKey{}).(bool); skip {
+		return
+	}
+
+	if d.logger != nil {
+		d.logger.With("table", db.Statement.Table).Warnf("postgres: %s", ErrNoDeadline)
+
+		return
+	}
+
+	_ = db.AddError(ErrNoDeadline)
+}
+
+// WarnWithoutDeadline makes statements without deadline logged instead of failed.
+func WarnWithoutDeadline(log logger.Logger) DeadlineCheckOpt {
+	return func(d *DeadlineCheck) {
+		d.logger = log
+	}
+}
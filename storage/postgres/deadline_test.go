@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+func TestDeadlineCheck(t *testing.T) {
+	db := dryRunDB(t)
+	require.NoError(t, db.Use(NewDeadlineCheck()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(t, db.WithContext(ctx).Find(&[]testUser{}).Error)
+	require.NoError(t, Conn(ctx, db).Exec("SELECT 1").Error)
+	require.NoError(t, db.WithContext(WithoutDeadline(context.Background())).Find(&[]testUser{}).Error)
+
+	// context of the request is not passed to the query
+	require.ErrorIs(t, db.Find(&[]testUser{}).Error, ErrNoDeadline)
+	require.ErrorIs(t, db.Exec("SELECT 1").Error, ErrNoDeadline)
+	require.ErrorIs(t, db.Create(&testUser{Name: "bob"}).Error, ErrNoDeadline)
+	require.ErrorIs(t, db.Model(&testUser{ID: 1}).Update("name", "bob").Error, ErrNoDeadline)
+}
+
+func TestDeadlineCheckWarning(t *testing.T) {
+	log := &recordLogger{}
+
+	db := dryRunDB(t)
+	require.NoError(t, db.Use(NewDeadlineCheck(WarnWithoutDeadline(log))))
+
+	require.NoError(t, db.Find(&[]testUser{}).Error)
+	require.Equal(t, []string{"postgres: " + ErrNoDeadline.Error()}, log.warnings)
+}
+
+type recordLogger struct {
+	logger.NullLogger
+
+	warnings []string
+}
+
+func (l *recordLogger) With(string, any) logger.Logger {
+	return l
+}
+
+func (l *recordLogger) Warnf(msg string, args ...any) {
+	l.warnings = append(l.warnings, fmt.Sprintf(msg, args...))
+}
@@ -1,7 +1,9 @@
 package postgres
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
@@ -12,6 +14,7 @@ import (
 const (
 	pgErrorCodeUniqueConstraint          = "23505"
 	pgErrorForeignKeyConstraintViolation = "23503"
+	pgErrorQueryCanceled                 = "57014"
 )
 
 func ActualError(err error) error {
@@ -31,8 +34,15 @@ func ActualError(err error) error {
 			return storage.ErrUniqueConstraintViolation
 		case pgErrorForeignKeyConstraintViolation:
 			return storage.ErrForeignKeyConstraintViolation
+		case pgErrorQueryCanceled:
+			return fmt.Errorf("%w: %w", storage.ErrQueryCanceled, err)
 		}
 	}
 
+	// the cause is kept, so callers can tell the deadline from the cancellation
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", storage.ErrQueryCanceled, err)
+	}
+
 	return err
 }
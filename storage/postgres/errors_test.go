@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+func TestActualErrorQueryCanceled(t *testing.T) {
+	err := ActualError(&pgconn.PgError{Code: pgErrorQueryCanceled})
+	require.ErrorIs(t, err, storage.ErrQueryCanceled)
+
+	err = ActualError(fmt.Errorf("timeout: %w", context.DeadlineExceeded))
+	require.ErrorIs(t, err, storage.ErrQueryCanceled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// tableIndex is an index of a table with configurable name, it is named after the table
+// because index names are unique per schema.
+type tableIndex struct {
+	suffix string
+	unique bool
+	// definition follows the table name, e.g. "(queue, run_at)"
+	definition string
+}
+
+func indexName(table, suffix string) string {
+	return "idx_" + strings.ReplaceAll(table, ".", "_") + "_" + suffix
+}
+
+func createIndexes(tx *gorm.DB, table string, indexes ...tableIndex) error {
+	for _, idx := range indexes {
+		name := indexName(table, idx.suffix)
+
+		unique := ""
+		if idx.unique {
+			unique = "UNIQUE "
+		}
+
+		sql := fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s %s",
+			unique, tx.Statement.Quote(name), tx.Statement.Quote(table), idx.definition)
+
+		if err := tx.Exec(sql).Error; err != nil {
+			return fmt.Errorf("create index %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
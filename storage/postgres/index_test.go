@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// recordStatements returns SQL of statements executed through 'db'.
+func recordStatements(t *testing.T, db *gorm.DB) *[]string {
+	t.Helper()
+
+	var statements []string
+
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	}
+
+	require.NoError(t, db.Callback().Raw().After("gorm:raw").Register("test:record", record))
+	require.NoError(t, db.Callback().Row().After("gorm:row").Register("test:record", record))
+
+	return &statements
+}
+
+func TestCreateIndexes(t *testing.T) {
+	db := dryRunDB(t)
+	statements := recordStatements(t, db)
+
+	require.NoError(t, createIndexes(db, "jobs", jobIndexes...))
+	require.NoError(t, createIndexes(db, "tenant.tasks", jobIndexes...))
+	require.NoError(t, createIndexes(db, "audit_log", auditIndexes...))
+
+	require.Equal(t, []string{
+		`CREATE INDEX IF NOT EXISTS "idx_jobs_fetch" ON "jobs" (queue, run_at)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "idx_jobs_unique_key" ON "jobs" (unique_key) WHERE failed_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS "idx_tenant_tasks_fetch" ON "tenant"."tasks" (queue, run_at)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "idx_tenant_tasks_unique_key" ON "tenant"."tasks" (unique_key) WHERE failed_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS "idx_audit_log_entity" ON "audit_log" (entity_type, entity_id)`,
+	}, *statements)
+}
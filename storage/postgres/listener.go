@@ -0,0 +1,298 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	defaultListenerBuffer     = 64
+	defaultListenerBackoff    = 100 * time.Millisecond
+	defaultListenerMaxBackoff = 30 * time.Second
+	listenerCloseTimeout      = 5 * time.Second
+
+	errListenerChanged = wherr.Error("listener channels changed")
+)
+
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32
+}
+
+type NotificationHandler func(ctx context.Context, n Notification)
+
+type ListenerOpt func(*Listener)
+
+func NewListener(dsn string, log logger.Logger, opts ...ListenerOpt) *Listener {
+	l := &Listener{
+		dsn:        dsn,
+		logger:     log,
+		channels:   make(map[string]struct{}),
+		changed:    make(chan struct{}, 1),
+		out:        make(chan Notification, defaultListenerBuffer),
+		backoff:    defaultListenerBackoff,
+		maxBackoff: defaultListenerMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Listener keeps a dedicated connection subscribed to postgres channels.
+// On connection loss it reconnects and subscribes to all channels again.
+type Listener struct {
+	dsn         string
+	logger      logger.Logger
+	handler     NotificationHandler
+	onReconnect func(ctx context.Context)
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	mu       sync.Mutex
+	channels map[string]struct{}
+	changed  chan struct{}
+	out      chan Notification
+}
+
+// Listen subscribes to channels. It may be called before or while Run is running.
+func (l *Listener) Listen(channels ...string) {
+	l.mu.Lock()
+	for _, ch := range channels {
+		l.channels[ch] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	l.notifyChanged()
+}
+
+func (l *Listener) Unlisten(channels ...string) {
+	l.mu.Lock()
+	for _, ch := range channels {
+		delete(l.channels, ch)
+	}
+	l.mu.Unlock()
+
+	l.notifyChanged()
+}
+
+// Notifications returns channel with received notifications, unless handler is set
+// with WithNotificationHandler. The channel is closed when Run returns.
+func (l *Listener) Notifications() <-chan Notification {
+	return l.out
+}
+
+// Run listens until ctx is cancelled.
+func (l *Listener) Run(ctx context.Context) error {
+	defer close(l.out)
+
+	l.logger.Infof("postgres listener started")
+	defer l.logger.Infof("postgres listener done")
+
+	var attempt int
+
+	for reconnect := false; ; reconnect = true {
+		connected, err := l.session(ctx, reconnect)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if connected {
+			attempt = 0
+		}
+
+		delay := backoff(attempt, l.backoff, l.maxBackoff)
+		attempt++
+
+		l.logger.WithError(err).Warnf("postgres listener connection lost, reconnecting in %s", delay)
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil
+		}
+	}
+}
+
+// session listens on a single connection and reports whether it was established.
+func (l *Listener) session(ctx context.Context, reconnect bool) (bool, error) {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return false, fmt.Errorf("postgres: listener: connect: %w", err)
+	}
+
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), listenerCloseTimeout)
+		defer cancel()
+
+		_ = conn.Close(closeCtx)
+	}()
+
+	listening := make(map[string]struct{})
+
+	if err := l.sync(ctx, conn, listening); err != nil {
+		return false, err
+	}
+
+	if reconnect && l.onReconnect != nil {
+		l.onReconnect(ctx)
+	}
+
+	for {
+		n, err := l.wait(ctx, conn.WaitForNotification)
+
+		switch {
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case errors.Is(err, errListenerChanged):
+			if err := l.sync(ctx, conn, listening); err != nil {
+				return true, err
+			}
+
+			continue
+		case err != nil:
+			return true, fmt.Errorf("postgres: listener: wait: %w", err)
+		}
+
+		l.deliver(ctx, Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID})
+	}
+}
+
+// wait blocks until notification is received or set of channels changes.
+func (l *Listener) wait(
+	ctx context.Context, waitFor func(context.Context) (*pgconn.Notification, error),
+) (*pgconn.Notification, error) {
+	waitCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	consumed := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-l.changed:
+			cancel(errListenerChanged)
+			consumed <- true
+		case <-done:
+			consumed <- false
+		}
+	}()
+
+	n, err := waitFor(waitCtx)
+
+	// the watcher may take the change after the wait returned, so its result is awaited
+	close(done)
+	changed := <-consumed
+
+	switch {
+	case err == nil && changed:
+		// change arrived together with notification, keep it for the next wait
+		l.notifyChanged()
+	case err != nil && changed:
+		return nil, errListenerChanged
+	case err != nil:
+		return nil, err // nolint: wrapcheck
+	}
+
+	return n, nil
+}
+
+// sync issues LISTEN/UNLISTEN, so connection is subscribed exactly to the configured channels.
+func (l *Listener) sync(ctx context.Context, conn *pgx.Conn, listening map[string]struct{}) error {
+	l.mu.Lock()
+	wanted := maps.Clone(l.channels)
+	l.mu.Unlock()
+
+	for ch := range wanted {
+		if _, ok := listening[ch]; ok {
+			continue
+		}
+
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return fmt.Errorf("postgres: listener: listen %q: %w", ch, err)
+		}
+
+		listening[ch] = struct{}{}
+	}
+
+	for ch := range listening {
+		if _, ok := wanted[ch]; ok {
+			continue
+		}
+
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return fmt.Errorf("postgres: listener: unlisten %q: %w", ch, err)
+		}
+
+		delete(listening, ch)
+	}
+
+	return nil
+}
+
+func (l *Listener) deliver(ctx context.Context, n Notification) {
+	if l.handler != nil {
+		l.handler(ctx, n)
+
+		return
+	}
+
+	select {
+	case l.out <- n:
+	case <-ctx.Done():
+	}
+}
+
+func (l *Listener) notifyChanged() {
+	select {
+	case l.changed <- struct{}{}:
+	default:
+	}
+}
+
+// WithNotificationHandler makes listener call 'h' for every notification instead
+// of sending it to the Notifications channel.
+func WithNotificationHandler(h NotificationHandler) ListenerOpt {
+	return func(l *Listener) {
+		l.handler = h
+	}
+}
+
+// WithReconnectHandler sets callback called after connection is restored.
+// Notifications sent while listener was disconnected are lost, so it is a
+// good place to e.g. drop caches.
+func WithReconnectHandler(fn func(ctx context.Context)) ListenerOpt {
+	return func(l *Listener) {
+		l.onReconnect = fn
+	}
+}
+
+func WithListenerBuffer(size int) ListenerOpt {
+	return func(l *Listener) {
+		l.out = make(chan Notification, size)
+	}
+}
+
+func WithListenerBackoff(backoff, maxBackoff time.Duration) ListenerOpt {
+	return func(l *Listener) {
+		l.backoff = backoff
+		l.maxBackoff = maxBackoff
+	}
+}
+
+// Notify sends notification using the transaction from ctx, so it is delivered only on commit.
+func Notify(ctx context.Context, db *gorm.DB, channel, payload string) error {
+	return ActualError(Conn(ctx, db).Exec("SELECT pg_notify(?, ?)", channel, payload).Error)
+}
@@ -0,0 +1,251 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	ErrLockNotAcquired = wherr.Error("lock not acquired")
+	ErrLockNotHeld     = wherr.Error("lock not held")
+
+	defaultElectionInterval = 5 * time.Second
+	unlockTimeout           = 5 * time.Second
+)
+
+func NewLocker(db *gorm.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// Locker provides session level advisory locks. Every held lock keeps its own
+// connection from the pool until it is unlocked.
+type Locker struct {
+	db *gorm.DB
+}
+
+// TryLock acquires the lock without waiting, returning ErrLockNotAcquired if it is held by someone else.
+func (l *Locker) TryLock(ctx context.Context, key string) (*Lock, error) {
+	return l.lock(ctx, key, "SELECT pg_try_advisory_lock($1)")
+}
+
+// Lock waits until the lock is acquired or ctx is done.
+func (l *Locker) Lock(ctx context.Context, key string) (*Lock, error) {
+	return l.lock(ctx, key, "SELECT true FROM pg_advisory_lock($1)")
+}
+
+func (l *Locker) Unlock(ctx context.Context, lock *Lock) error {
+	return lock.Unlock(ctx)
+}
+
+func (l *Locker) lock(ctx context.Context, key, query string) (*Lock, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("postgres: get connection pool: %w", err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: lock %q: get connection: %w", key, err)
+	}
+
+	id := advisoryKey(key)
+
+	var acquired bool
+
+	if err := conn.QueryRowContext(ctx, query, id).Scan(&acquired); err != nil {
+		// the lock may be acquired even if reading the result failed
+		discard(conn)
+
+		return nil, fmt.Errorf("postgres: lock %q: %w", key, err)
+	}
+
+	if !acquired {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("%w: %q", ErrLockNotAcquired, key)
+	}
+
+	return &Lock{key: key, id: id, conn: conn}, nil
+}
+
+type Lock struct {
+	key  string
+	id   int64
+	conn *sql.Conn
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Unlock releases the lock and returns its connection to the pool. The lock is released
+// even if ctx is done, if that fails the connection is closed, so the server releases it.
+func (l *Lock) Unlock(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlockTimeout)
+	defer cancel()
+
+	var released bool
+
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.id).Scan(&released); err != nil {
+		discard(l.conn)
+
+		return fmt.Errorf("postgres: unlock %q: %w", l.key, err)
+	}
+
+	if err := l.conn.Close(); err != nil {
+		return fmt.Errorf("postgres: unlock %q: %w", l.key, err)
+	}
+
+	if !released {
+		return fmt.Errorf("%w: %q", ErrLockNotHeld, l.key)
+	}
+
+	return nil
+}
+
+// discard closes the physical connection instead of returning it to the pool, session
+// locks would stay held by the idle pooled connection otherwise.
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+// alive checks that the connection holding the lock is still open.
+func (l *Lock) alive(ctx context.Context) error {
+	return l.conn.PingContext(ctx) // nolint: wrapcheck
+}
+
+type LeaderElectorOpt func(*LeaderElector)
+
+func NewLeaderElector(db *gorm.DB, key string, log logger.Logger, opts ...LeaderElectorOpt) *LeaderElector {
+	e := &LeaderElector{
+		locker:    NewLocker(db),
+		key:       key,
+		logger:    log.With("election", key),
+		interval:  defaultElectionInterval,
+		onElected: func(context.Context) {},
+		onRevoked: func() {},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// LeaderElector makes a single replica the leader by holding an advisory lock.
+type LeaderElector struct {
+	locker    *Locker
+	key       string
+	logger    logger.Logger
+	interval  time.Duration
+	onElected func(ctx context.Context)
+	onRevoked func()
+	leader    atomic.Bool
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run takes part in the election until ctx is cancelled, e.g. the one from
+// OSInterruptContext, releasing the leadership before returning.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	for {
+		lock, err := e.locker.TryLock(ctx, e.key)
+
+		switch {
+		case err == nil:
+			e.lead(ctx, lock)
+		case errors.Is(err, ErrLockNotAcquired), ctx.Err() != nil:
+		default:
+			e.logger.WithError(err).Warnf("leader election failed")
+		}
+
+		if err := sleepContext(ctx, e.interval); err != nil {
+			return nil
+		}
+	}
+}
+
+// lead calls OnElected and holds the lock until ctx is done or its connection is lost.
+func (e *LeaderElector) lead(ctx context.Context, lock *Lock) {
+	e.logger.Infof("elected as leader")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	e.leader.Store(true)
+
+	go func() {
+		defer close(done)
+
+		e.onElected(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		if err := lock.alive(ctx); err != nil {
+			if ctx.Err() == nil {
+				e.logger.WithError(err).Warnf("leader lock connection lost")
+			}
+
+			break loop
+		}
+	}
+
+	cancel()
+	<-done
+
+	e.leader.Store(false)
+
+	if err := lock.Unlock(ctx); err != nil {
+		e.logger.WithError(err).Debugf("leader lock release failed")
+	}
+
+	e.onRevoked()
+
+	e.logger.Infof("leadership revoked")
+}
+
+// WithOnElected sets callback started when leadership is acquired, its context
+// is cancelled when leadership is lost.
+func WithOnElected(fn func(ctx context.Context)) LeaderElectorOpt {
+	return func(e *LeaderElector) {
+		e.onElected = fn
+	}
+}
+
+// WithOnRevoked sets callback called after leadership is lost and OnElected callback returned.
+func WithOnRevoked(fn func()) LeaderElectorOpt {
+	return func(e *LeaderElector) {
+		e.onRevoked = fn
+	}
+}
+
+// WithElectionInterval sets how often leadership is tried to be acquired and its connection checked.
+func WithElectionInterval(interval time.Duration) LeaderElectorOpt {
+	return func(e *LeaderElector) {
+		e.interval = interval
+	}
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+func TestLeaderElectorStops(t *testing.T) {
+	var elected bool
+
+	e := NewLeaderElector(dryRunDB(t), "cron", logger.NewNullLogger(),
+		WithElectionInterval(time.Millisecond),
+		WithOnElected(func(context.Context) { elected = true }),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, e.Run(ctx))
+	require.False(t, elected)
+	require.False(t, e.IsLeader())
+}
+
+func TestLockerTryLockConnectionError(t *testing.T) {
+	_, err := NewLocker(dryRunDB(t)).TryLock(context.Background(), "cron")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrLockNotAcquired)
+}
+
+func TestLockUnlockCancelledContext(t *testing.T) {
+	server := newFakeLockServer()
+	locker, other := NewLocker(server.open(t)), NewLocker(server.open(t))
+
+	lock, err := locker.TryLock(context.Background(), "cron")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, lock.Unlock(ctx))
+
+	lock, err = other.TryLock(context.Background(), "cron")
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(context.Background()))
+}
+
+func TestLockUnlockFailureClosesConnection(t *testing.T) {
+	server := newFakeLockServer()
+	locker, other := NewLocker(server.open(t)), NewLocker(server.open(t))
+
+	lock, err := locker.TryLock(context.Background(), "cron")
+	require.NoError(t, err)
+
+	server.failUnlock.Store(true)
+	require.Error(t, lock.Unlock(context.Background()))
+	server.failUnlock.Store(false)
+
+	// lock is released by the server with the closed session, not kept by a pooled connection
+	lock, err = other.TryLock(context.Background(), "cron")
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(context.Background()))
+}
+
+// fakeLockServer emulates session advisory locks: a lock is held by its connection
+// until it is unlocked or the connection is closed.
+type fakeLockServer struct {
+	mu         sync.Mutex
+	locks      map[int64]*fakeLockConn
+	failUnlock atomic.Bool
+}
+
+func newFakeLockServer() *fakeLockServer {
+	return &fakeLockServer{locks: make(map[int64]*fakeLockConn)}
+}
+
+func (s *fakeLockServer) open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	sqlDB := sql.OpenDB(s)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               gormlogger.Discard,
+	})
+	require.NoError(t, err)
+
+	return db
+}
+
+func (s *fakeLockServer) Connect(context.Context) (driver.Conn, error) {
+	return &fakeLockConn{server: s}, nil
+}
+
+func (s *fakeLockServer) Driver() driver.Driver {
+	return fakeLockDriver{}
+}
+
+type fakeLockDriver struct{}
+
+func (fakeLockDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("use connector")
+}
+
+type fakeLockConn struct {
+	server *fakeLockServer
+}
+
+func (c *fakeLockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := c.server
+	id, _ := args[0].Value.(int64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.Contains(query, "pg_advisory_unlock") {
+		if s.failUnlock.Load() {
+			return nil, errors.New("connection reset by peer")
+		}
+
+		held := s.locks[id] == c
+		if held {
+			delete(s.locks, id)
+		}
+
+		return &fakeRows{value: held}, nil
+	}
+
+	holder, ok := s.locks[id]
+	if !ok {
+		s.locks[id] = c
+	}
+
+	return &fakeRows{value: !ok || holder == c}, nil
+}
+
+func (c *fakeLockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	for id, holder := range c.server.locks {
+		if holder == c {
+			delete(c.server.locks, id)
+		}
+	}
+
+	return nil
+}
+
+func (c *fakeLockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeLockConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeRows struct {
+	value any
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	r.done = true
+	dest[0] = r.value
+
+	return nil
+}
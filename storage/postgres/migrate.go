@@ -0,0 +1,410 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	ErrInvalidMigration     = wherr.Error("invalid migration")
+	ErrMissingDownMigration = wherr.Error("missing down migration")
+
+	defaultMigrationsTable = "schema_migrations"
+)
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_([\w-]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned migration read from files named
+// '<version>_<name>.up.sql' and '<version>_<name>.down.sql', names consist of letters,
+// digits, '_' and '-'. Other .sql files and empty migrations are rejected.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type MigratorOpt func(*Migrator)
+
+func NewMigrator(db *gorm.DB, fsys fs.FS, opts ...MigratorOpt) (*Migrator, error) {
+	migrations, err := parseMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:         db,
+		migrations: migrations,
+		table:      defaultMigrationsTable,
+		logger:     logger.NewNullLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	table      string
+	dryRun     bool
+	logger     logger.Logger
+}
+
+// Up applies all pending migrations and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var result []Migration
+
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.run(conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+
+			result = append(result, migration)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Down reverts the last 'n' applied migrations and returns them in the order they were reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var result []Migration
+
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(result) < n; i-- {
+			if _, ok := applied[m.migrations[i].Version]; !ok {
+				continue
+			}
+
+			if m.migrations[i].Down == "" {
+				return fmt.Errorf("%w: version %d", ErrMissingDownMigration, m.migrations[i].Version)
+			}
+
+			result = append(result, m.migrations[i])
+		}
+
+		for _, migration := range result {
+			if err := m.run(conn, migration, migration.Down, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var result []MigrationStatus
+
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+
+			if row, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = row.AppliedAt
+
+				delete(applied, migration.Version)
+			}
+
+			result = append(result, status)
+		}
+
+		// applied migrations that are no longer present in the source
+		for _, row := range applied {
+			result = append(result, MigrationStatus{
+				Version:   row.Version,
+				Name:      row.Name,
+				Applied:   true,
+				AppliedAt: row.AppliedAt,
+			})
+		}
+
+		slices.SortFunc(result, func(a, b MigrationStatus) int {
+			return cmp.Compare(a.Version, b.Version)
+		})
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	key := advisoryKey("migrations:" + m.table)
+
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) (err error) { // nolint: nonamedreturns
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+			return fmt.Errorf("postgres: migrations: acquire lock: %w", err)
+		}
+
+		defer func() {
+			// use fresh context, so lock is released even if ctx is cancelled
+			unlockErr := conn.WithContext(context.WithoutCancel(ctx)).
+				Exec("SELECT pg_advisory_unlock(?)", key).Error
+			if unlockErr != nil {
+				err = errors.Join(err, fmt.Errorf("postgres: migrations: release lock: %w", unlockErr))
+			}
+		}()
+
+		if !m.dryRun {
+			if err := m.ensureTable(conn); err != nil {
+				return err
+			}
+		}
+
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	query := "CREATE TABLE IF NOT EXISTS " + m.quotedTable() + ` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+
+	if err := conn.Exec(query).Error; err != nil {
+		return fmt.Errorf("postgres: migrations: create table: %w", err)
+	}
+
+	return nil
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]appliedMigration, error) {
+	result := make(map[int64]appliedMigration)
+
+	if m.dryRun && !conn.Migrator().HasTable(m.table) {
+		return result, nil
+	}
+
+	var rows []appliedMigration
+
+	err := conn.Raw("SELECT version, name, applied_at FROM " + m.quotedTable()).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("postgres: migrations: read applied: %w", err)
+	}
+
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) run(conn *gorm.DB, migration Migration, query string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	log := m.logger.With("version", migration.Version).With("name", migration.Name)
+
+	if m.dryRun {
+		log.Infof("migration %s (dry run)", direction)
+
+		return nil
+	}
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if strings.TrimSpace(query) != "" {
+			if err := tx.Exec(query).Error; err != nil {
+				return err
+			}
+		}
+
+		if up {
+			return tx.Exec(
+				"INSERT INTO "+m.quotedTable()+" (version, name) VALUES (?, ?)",
+				migration.Version, migration.Name,
+			).Error
+		}
+
+		return tx.Exec("DELETE FROM "+m.quotedTable()+" WHERE version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("postgres: migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	log.Infof("migration %s applied", direction)
+
+	return nil
+}
+
+func (m *Migrator) quotedTable() string {
+	return pgx.Identifier(strings.Split(m.table, ".")).Sanitize()
+}
+
+func WithMigrationsTable(table string) MigratorOpt {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithDryRun makes migrator only report migrations it would run.
+func WithDryRun() MigratorOpt {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+func WithMigrationsLogger(log logger.Logger) MigratorOpt {
+	return func(m *Migrator) {
+		m.logger = log
+	}
+}
+
+func parseMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("postgres: read migrations: %w", err)
+	}
+
+	type versionFile struct {
+		version   int64
+		direction string
+	}
+
+	var (
+		byVersion = make(map[int64]*Migration)
+		// files by version and direction, e.g. 1_init.up.sql and 0001_init.up.sql are the same one
+		files = make(map[versionFile]string)
+	)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			if strings.HasSuffix(entry.Name(), ".sql") {
+				return nil, fmt.Errorf("%w: %q is not named <version>_<name>.(up|down).sql",
+					ErrInvalidMigration, entry.Name())
+			}
+
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidMigration, entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("postgres: read migration %q: %w", entry.Name(), err)
+		}
+
+		file := versionFile{version: version, direction: match[3]}
+		if other, ok := files[file]; ok {
+			return nil, fmt.Errorf("%w: %q and %q are both %s migrations of version %d",
+				ErrInvalidMigration, other, entry.Name(), file.direction, version)
+		}
+
+		files[file] = entry.Name()
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has different names: %q and %q",
+				ErrInvalidMigration, version, migration.Name, match[2])
+		}
+
+		if strings.TrimSpace(string(data)) == "" {
+			return nil, fmt.Errorf("%w: %q is empty", ErrInvalidMigration, entry.Name())
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if _, ok := files[versionFile{version: migration.Version, direction: "up"}]; !ok {
+			return nil, fmt.Errorf("%w: version %d has no up migration", ErrInvalidMigration, migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return int64(h.Sum64()) // nolint: gosec
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMigrations(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+			"0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP email;")},
+			"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+			"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+			"0010_seed.up.sql":           {Data: []byte("INSERT INTO users VALUES (1);")},
+			"0011_add-index.up.sql":      {Data: []byte("CREATE INDEX ON users (id);")},
+			"README.md":                  {Data: []byte("not a migration")},
+		}
+
+		migrations, err := parseMigrations(fsys)
+		require.NoError(t, err)
+		require.Equal(t, []Migration{
+			{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INT);", Down: "DROP TABLE users;"},
+			{Version: 2, Name: "add_email", Up: "ALTER TABLE users ADD email TEXT;", Down: "ALTER TABLE users DROP email;"},
+			{Version: 10, Name: "seed", Up: "INSERT INTO users VALUES (1);"},
+			{Version: 11, Name: "add-index", Up: "CREATE INDEX ON users (id);"},
+		}, migrations)
+	})
+
+	t.Run("missing up", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := parseMigrations(fsys)
+		require.ErrorIs(t, err, ErrInvalidMigration)
+	})
+
+	t.Run("name mismatch", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_users.up.sql":  {Data: []byte("CREATE TABLE users (id INT);")},
+			"0001_create_user.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := parseMigrations(fsys)
+		require.ErrorIs(t, err, ErrInvalidMigration)
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+			"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id BIGINT);")},
+		}
+
+		_, err := parseMigrations(fsys)
+		require.ErrorIs(t, err, ErrInvalidMigration)
+		require.ErrorContains(t, err, `"0001_create_users.up.sql" and "1_create_users.up.sql"`)
+
+		// up and down of the same version may use different formatting of the version
+		fsys = fstest.MapFS{
+			"1_create_users.up.sql":      {Data: []byte("CREATE TABLE users (id INT);")},
+			"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		migrations, err := parseMigrations(fsys)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+
+		fsys = fstest.MapFS{
+			"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+			"0001_init.down.sql":    {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err = parseMigrations(fsys)
+		require.ErrorIs(t, err, ErrInvalidMigration)
+	})
+
+	for name, file := range map[string]string{
+		"unrecognized name": "0003_create users.up.sql",
+		"missing version":   "create_users.up.sql",
+		"unknown direction": "0003_create_users.sql",
+	} {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+				file:                       {Data: []byte("SELECT 1;")},
+			}
+
+			_, err := parseMigrations(fsys)
+			require.ErrorIs(t, err, ErrInvalidMigration)
+			require.ErrorContains(t, err, file)
+		})
+	}
+
+	t.Run("empty up", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_users.up.sql":   {Data: []byte(" \n")},
+			"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := parseMigrations(fsys)
+		require.ErrorIs(t, err, ErrInvalidMigration)
+		require.ErrorContains(t, err, `"0001_create_users.up.sql" is empty`)
+	})
+}
@@ -1,6 +1,14 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
@@ -8,20 +16,113 @@ import (
 	"github.com/bohdanch-w/wheel/logger"
 )
 
-type DBOption func(*gorm.Config)
+const (
+	defaultPingAttempts   = 1
+	defaultPingBackoff    = 500 * time.Millisecond
+	defaultPingMaxBackoff = 10 * time.Second
+)
+
+// DBOption configures Open. It used to be func(*gorm.Config), such options are
+// passed with WithGormConfig.
+type DBOption func(*dbConfig)
+
+type dbConfig struct {
+	gorm gorm.Config
+
+	maxOpenConns     int
+	maxIdleConns     int
+	connMaxLifetime  time.Duration
+	connMaxIdleTime  time.Duration
+	statementTimeout time.Duration
+
+	pingAttempts   int
+	pingBackoff    time.Duration
+	pingMaxBackoff time.Duration
+
+	replicas             []string
+	replicaPolicy        ReplicaPolicy
+	replicaCheckInterval time.Duration
+	replicaMaxFailures   int
+}
 
 func Open(dsn string, opts ...DBOption) (*gorm.DB, error) {
-	cfg := gorm.Config{}
+	return OpenContext(context.Background(), dsn, opts...)
+}
+
+// OpenContext opens the database and pings it until it responds, the
+// configured number of attempts is exhausted or ctx is done.
+// Database with replicas must be closed with Close.
+func OpenContext(ctx context.Context, dsn string, opts ...DBOption) (*gorm.DB, error) {
+	cfg := dbConfig{
+		pingAttempts:   defaultPingAttempts,
+		pingBackoff:    defaultPingBackoff,
+		pingMaxBackoff: defaultPingMaxBackoff,
+	}
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
-	return gorm.Open(postgres.Open(dsn), &cfg) // nolint: wrapcheck
+	// ping is done below with retries
+	cfg.gorm.DisableAutomaticPing = true
+
+	sqlDB, err := openPool(dsn, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &cfg.gorm)
+	if err != nil {
+		_ = sqlDB.Close()
+
+		return nil, fmt.Errorf("postgres: open: %w", err)
+	}
+
+	if err := pingWithRetry(ctx, sqlDB, &cfg); err != nil {
+		_ = sqlDB.Close()
+
+		return nil, err
+	}
+
+	if len(cfg.replicas) > 0 {
+		router, err := openReplicas(&cfg)
+		if err == nil {
+			err = db.Use(router)
+		}
+
+		if err != nil {
+			_ = sqlDB.Close()
+
+			return nil, fmt.Errorf("postgres: replicas: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+func HealthCheck(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("postgres: get connection pool: %w", err)
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("postgres: ping: %w", err)
+	}
+
+	return nil
+}
+
+// HealthChecker returns HealthCheck of 'db' usable as health.CheckFunc, register it
+// with health.WithReadinessOnly to keep database outages out of liveness probes.
+func HealthChecker(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return HealthCheck(ctx, db)
+	}
 }
 
 func WithLogLevel(level logger.LogLevel) DBOption {
-	return func(config *gorm.Config) {
+	return func(config *dbConfig) {
 		dbLogger := gormlogger.Discard
 
 		switch level {
@@ -35,6 +136,103 @@ func WithLogLevel(level logger.LogLevel) DBOption {
 			dbLogger = gormlogger.Default.LogMode(gormlogger.Silent)
 		}
 
-		config.Logger = dbLogger
+		config.gorm.Logger = dbLogger
 	}
 }
+
+// WithGormConfig adapts function modifying gorm.Config, e.g. a DBOption of earlier versions.
+func WithGormConfig(fn func(*gorm.Config)) DBOption {
+	return func(config *dbConfig) {
+		fn(&config.gorm)
+	}
+}
+
+func WithMaxOpenConns(n int) DBOption {
+	return func(config *dbConfig) {
+		config.maxOpenConns = n
+	}
+}
+
+func WithMaxIdleConns(n int) DBOption {
+	return func(config *dbConfig) {
+		config.maxIdleConns = n
+	}
+}
+
+func WithConnMaxLifetime(d time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.connMaxLifetime = d
+	}
+}
+
+func WithConnMaxIdleTime(d time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.connMaxIdleTime = d
+	}
+}
+
+// WithStatementTimeout sets postgres statement_timeout for every connection in the pool.
+func WithStatementTimeout(d time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.statementTimeout = d
+	}
+}
+
+// WithConnectRetry makes Open ping the database up to 'attempts' times,
+// doubling the delay between attempts starting from 'backoff' up to 'maxBackoff'.
+func WithConnectRetry(attempts int, backoff, maxBackoff time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.pingAttempts = max(attempts, 1)
+		config.pingBackoff = backoff
+		config.pingMaxBackoff = maxBackoff
+	}
+}
+
+func openPool(dsn string, cfg *dbConfig) (*sql.DB, error) {
+	connCfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: parse dsn: %w", err)
+	}
+
+	if cfg.statementTimeout > 0 {
+		connCfg.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.statementTimeout.Milliseconds(), 10)
+	}
+
+	sqlDB := stdlib.OpenDB(*connCfg)
+
+	if cfg.maxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.maxOpenConns)
+	}
+
+	if cfg.maxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.maxIdleConns)
+	}
+
+	if cfg.connMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.connMaxLifetime)
+	}
+
+	if cfg.connMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.connMaxIdleTime)
+	}
+
+	return sqlDB, nil
+}
+
+func pingWithRetry(ctx context.Context, sqlDB *sql.DB, cfg *dbConfig) error {
+	var err error
+
+	for attempt := range cfg.pingAttempts {
+		if attempt > 0 {
+			if sleepErr := sleepContext(ctx, backoff(attempt-1, cfg.pingBackoff, cfg.pingMaxBackoff)); sleepErr != nil {
+				return fmt.Errorf("postgres: ping: %w (last error: %w)", sleepErr, err)
+			}
+		}
+
+		if err = sqlDB.PingContext(ctx); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("postgres: ping failed after %d attempts: %w", cfg.pingAttempts, err)
+}
@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	defaultOutboxTable        = "outbox_messages"
+	defaultOutboxBatchSize    = 100
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxBackoff      = time.Second
+	defaultOutboxMaxBackoff   = 10 * time.Minute
+)
+
+type OutboxMessage struct {
+	ID             uuid.UUID         `gorm:"type:uuid;primaryKey"`
+	Topic          string            `gorm:"not null"`
+	Key            string            `gorm:"not null;default:''"`
+	Payload        []byte            `gorm:"not null"`
+	Headers        map[string]string `gorm:"type:jsonb;serializer:json"`
+	Attempts       int               `gorm:"not null;default:0"`
+	LastError      string            `gorm:"not null;default:''"`
+	CreatedAt      time.Time         `gorm:"not null"`
+	NextAttemptAt  time.Time         `gorm:"not null;index"`
+	DeadLetteredAt *time.Time
+}
+
+type OutboxPublisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+type OutboxPublisherFunc func(ctx context.Context, msg OutboxMessage) error
+
+func (f OutboxPublisherFunc) Publish(ctx context.Context, msg OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+type OutboxOpt func(*Outbox)
+
+func NewOutbox(db *gorm.DB, opts ...OutboxOpt) *Outbox {
+	outbox := &Outbox{
+		db:    db,
+		table: defaultOutboxTable,
+	}
+
+	for _, opt := range opts {
+		opt(outbox)
+	}
+
+	return outbox
+}
+
+// Outbox stores messages in the same transaction as the business data,
+// so they are published only if that transaction commits.
+type Outbox struct {
+	db    *gorm.DB
+	table string
+}
+
+func (o *Outbox) Migrate(ctx context.Context) error {
+	if err := o.db.WithContext(ctx).Table(o.table).AutoMigrate(&OutboxMessage{}); err != nil {
+		return fmt.Errorf("postgres: outbox: migrate: %w", err)
+	}
+
+	return nil
+}
+
+// Add writes messages using the transaction from ctx, see Transaction.
+func (o *Outbox) Add(ctx context.Context, messages ...OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	for i := range messages {
+		if messages[i].ID == uuid.Nil {
+			messages[i].ID = uuid.New()
+		}
+
+		if messages[i].CreatedAt.IsZero() {
+			messages[i].CreatedAt = now
+		}
+
+		if messages[i].NextAttemptAt.IsZero() {
+			messages[i].NextAttemptAt = messages[i].CreatedAt
+		}
+	}
+
+	return ActualError(Conn(ctx, o.db).Table(o.table).Create(&messages).Error)
+}
+
+func (o *Outbox) DeadLetters(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+
+	err := Conn(ctx, o.db).Table(o.table).
+		Where("dead_lettered_at IS NOT NULL").
+		Order("dead_lettered_at").
+		Limit(limit).
+		Find(&messages).Error
+
+	return messages, ActualError(err)
+}
+
+// Requeue returns dead lettered messages back to dispatching with reset attempts.
+func (o *Outbox) Requeue(ctx context.Context, ids ...uuid.UUID) error {
+	err := Conn(ctx, o.db).Table(o.table).
+		Where("id IN ? AND dead_lettered_at IS NOT NULL", ids).
+		Updates(map[string]any{
+			"attempts":         0,
+			"last_error":       "",
+			"next_attempt_at":  time.Now(),
+			"dead_lettered_at": nil,
+		}).Error
+
+	return ActualError(err)
+}
+
+func WithOutboxTable(table string) OutboxOpt {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
+
+type OutboxDispatcherOpt func(*OutboxDispatcher)
+
+func NewOutboxDispatcher(
+	outbox *Outbox,
+	publisher OutboxPublisher,
+	log logger.Logger,
+	opts ...OutboxDispatcherOpt,
+) *OutboxDispatcher {
+	d := &OutboxDispatcher{
+		outbox:       outbox,
+		publisher:    publisher,
+		logger:       log,
+		batchSize:    defaultOutboxBatchSize,
+		pollInterval: defaultOutboxPollInterval,
+		maxAttempts:  defaultOutboxMaxAttempts,
+		backoff:      defaultOutboxBackoff,
+		maxBackoff:   defaultOutboxMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// OutboxDispatcher publishes stored messages. Several dispatchers may run
+// concurrently, rows are distributed between them with SKIP LOCKED.
+type OutboxDispatcher struct {
+	outbox       *Outbox
+	publisher    OutboxPublisher
+	logger       logger.Logger
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+}
+
+// Run dispatches messages until ctx is cancelled.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	d.logger.Infof("outbox dispatcher started")
+	defer d.logger.Infof("outbox dispatcher done")
+
+	for {
+		n, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.WithError(err).Errorf("outbox dispatch failed")
+		}
+
+		// full batch means there are likely more messages waiting
+		if err == nil && n == d.batchSize {
+			continue
+		}
+
+		if err := sleepContext(ctx, d.pollInterval); err != nil {
+			return nil
+		}
+	}
+}
+
+// Dispatch publishes a single batch of due messages and returns its size.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	var count int
+
+	err := d.outbox.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var messages []OutboxMessage
+
+		err := tx.Table(d.outbox.table).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dead_lettered_at IS NULL AND next_attempt_at <= ?", time.Now()).
+			Order("created_at, id").
+			Limit(d.batchSize).
+			Find(&messages).Error
+		if err != nil {
+			return err
+		}
+
+		count = len(messages)
+
+		for _, msg := range messages {
+			if err := d.publish(ctx, tx, msg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return count, ActualError(err)
+}
+
+func (d *OutboxDispatcher) publish(ctx context.Context, tx *gorm.DB, msg OutboxMessage) error {
+	log := d.logger.With("outbox_id", msg.ID).With("topic", msg.Topic)
+
+	publishErr := d.publisher.Publish(ctx, msg)
+	if publishErr == nil {
+		return tx.Table(d.outbox.table).Where("id = ?", msg.ID).Delete(&OutboxMessage{}).Error
+	}
+
+	if errors.Is(publishErr, context.Canceled) && ctx.Err() != nil {
+		return publishErr
+	}
+
+	updates := d.failureUpdates(msg, publishErr, time.Now())
+
+	if _, dead := updates["dead_lettered_at"]; dead {
+		log.WithError(publishErr).Errorf("outbox message dead lettered after %d attempts", msg.Attempts+1)
+	} else {
+		log.WithError(publishErr).Warnf("outbox message publish failed, attempt %d", msg.Attempts+1)
+	}
+
+	return tx.Table(d.outbox.table).Where("id = ?", msg.ID).Updates(updates).Error
+}
+
+func (d *OutboxDispatcher) failureUpdates(msg OutboxMessage, err error, now time.Time) map[string]any {
+	attempts := msg.Attempts + 1
+
+	updates := map[string]any{
+		"attempts":        attempts,
+		"last_error":      err.Error(),
+		"next_attempt_at": now.Add(backoff(attempts-1, d.backoff, d.maxBackoff)),
+	}
+
+	if attempts >= d.maxAttempts {
+		updates["dead_lettered_at"] = now
+	}
+
+	return updates
+}
+
+func WithOutboxBatchSize(n int) OutboxDispatcherOpt {
+	return func(d *OutboxDispatcher) {
+		d.batchSize = max(n, 1)
+	}
+}
+
+func WithOutboxPollInterval(interval time.Duration) OutboxDispatcherOpt {
+	return func(d *OutboxDispatcher) {
+		d.pollInterval = interval
+	}
+}
+
+// WithOutboxRetry sets how many times message is published before being dead lettered
+// and the exponential backoff between attempts.
+func WithOutboxRetry(maxAttempts int, backoff, maxBackoff time.Duration) OutboxDispatcherOpt {
+	return func(d *OutboxDispatcher) {
+		d.maxAttempts = max(maxAttempts, 1)
+		d.backoff = backoff
+		d.maxBackoff = maxBackoff
+	}
+}
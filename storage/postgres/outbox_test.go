@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+func TestOutboxAdd(t *testing.T) {
+	db := dryRunDB(t)
+	outbox := NewOutbox(db, WithOutboxTable("events"))
+
+	var sql string
+
+	err := db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	require.NoError(t, err)
+
+	err = outbox.Add(context.Background(), OutboxMessage{Topic: "user.created", Payload: []byte(`{"id":1}`)})
+	require.NoError(t, err)
+	require.Contains(t, sql, `INSERT INTO "events"`)
+}
+
+func TestOutboxFailureUpdates(t *testing.T) {
+	var (
+		now        = time.Now()
+		errPublish = wherr.Error("broker unavailable")
+		dispatcher = NewOutboxDispatcher(
+			NewOutbox(nil), nil, logger.NewNullLogger(),
+			WithOutboxRetry(3, time.Second, time.Minute),
+		)
+	)
+
+	updates := dispatcher.failureUpdates(OutboxMessage{Attempts: 0}, errPublish, now)
+	require.Equal(t, map[string]any{
+		"attempts":        1,
+		"last_error":      "broker unavailable",
+		"next_attempt_at": now.Add(time.Second),
+	}, updates)
+
+	updates = dispatcher.failureUpdates(OutboxMessage{Attempts: 1}, errPublish, now)
+	require.Equal(t, now.Add(2*time.Second), updates["next_attempt_at"])
+	require.NotContains(t, updates, "dead_lettered_at")
+
+	updates = dispatcher.failureUpdates(OutboxMessage{Attempts: 2}, errPublish, now)
+	require.Equal(t, now, updates["dead_lettered_at"])
+}
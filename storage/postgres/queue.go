@@ -0,0 +1,501 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	ErrDuplicateJob = wherr.Error("duplicate job")
+	ErrNoJobHandler = wherr.Error("no job handler")
+
+	errJobAbandoned = wherr.Error("visibility timeout expired on the last attempt")
+
+	DefaultQueue = "default"
+
+	defaultJobsTable         = "jobs"
+	defaultJobMaxAttempts    = 5
+	defaultJobWorkers        = 1
+	defaultJobPollInterval   = time.Second
+	defaultJobVisibility     = 5 * time.Minute
+	defaultJobBackoff        = 5 * time.Second
+	defaultJobMaxBackoff     = time.Hour
+	jobReleaseTimeout        = 5 * time.Second
+	minJobVisibility         = time.Second
+	jobVisibilityRenewFactor = 2
+)
+
+var jobIndexes = []tableIndex{ // nolint: gochecknoglobals
+	{suffix: "fetch", definition: "(queue, run_at)"},
+	{suffix: "unique_key", unique: true, definition: "(unique_key) WHERE failed_at IS NULL"},
+}
+
+type Job struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Queue       string          `gorm:"not null"`
+	Kind        string          `gorm:"not null"`
+	Payload     json.RawMessage `gorm:"type:jsonb;not null"`
+	UniqueKey   *string
+	Attempts    int       `gorm:"not null;default:0"`
+	MaxAttempts int       `gorm:"not null"`
+	LastError   string    `gorm:"not null;default:''"`
+	RunAt       time.Time `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+	FailedAt    *time.Time
+}
+
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("postgres: decode job %s payload: %w", j.ID, err)
+	}
+
+	return nil
+}
+
+type QueueOpt func(*Queue)
+
+func NewQueue(db *gorm.DB, opts ...QueueOpt) *Queue {
+	q := &Queue{
+		db:    db,
+		table: defaultJobsTable,
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Queue stores jobs in postgres. Job becomes invisible to other workers for
+// the visibility timeout once claimed, so jobs of crashed workers are retried.
+type Queue struct {
+	db    *gorm.DB
+	table string
+}
+
+func (q *Queue) Migrate(ctx context.Context) error {
+	tx := q.db.WithContext(ctx)
+
+	if err := tx.Table(q.table).AutoMigrate(&Job{}); err != nil {
+		return fmt.Errorf("postgres: jobs: migrate: %w", err)
+	}
+
+	if err := createIndexes(tx, q.table, jobIndexes...); err != nil {
+		return fmt.Errorf("postgres: jobs: migrate: %w", err)
+	}
+
+	return nil
+}
+
+type EnqueueOpt func(*Job)
+
+// Enqueue stores the job using the transaction from ctx if there is one.
+// Payload is marshalled to JSON.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOpt) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: encode job payload: %w", err)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:          uuid.New(),
+		Queue:       DefaultQueue,
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: defaultJobMaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	tx := Conn(ctx, q.db).Table(q.table)
+
+	if job.UniqueKey != nil {
+		tx = tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "unique_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "failed_at IS NULL"}}},
+			DoNothing:   true,
+		})
+	}
+
+	res := tx.Create(job)
+	if res.Error != nil {
+		return nil, ActualError(res.Error)
+	}
+
+	if job.UniqueKey != nil && res.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateJob, *job.UniqueKey)
+	}
+
+	return job, nil
+}
+
+// Failed returns jobs which exhausted their attempts.
+func (q *Queue) Failed(ctx context.Context, limit int) ([]Job, error) {
+	var jobs []Job
+
+	err := Conn(ctx, q.db).Table(q.table).
+		Where("failed_at IS NOT NULL").
+		Order("failed_at").
+		Limit(limit).
+		Find(&jobs).Error
+
+	return jobs, ActualError(err)
+}
+
+// Retry schedules failed jobs to run again with reset attempts.
+func (q *Queue) Retry(ctx context.Context, ids ...uuid.UUID) error {
+	err := Conn(ctx, q.db).Table(q.table).
+		Where("id IN ? AND failed_at IS NOT NULL", ids).
+		Updates(map[string]any{
+			"attempts":   0,
+			"last_error": "",
+			"run_at":     time.Now(),
+			"failed_at":  nil,
+		}).Error
+
+	return ActualError(err)
+}
+
+// claim locks up to 'limit' due jobs, making them invisible until 'visibleAt'. Jobs
+// which were claimed for their last attempt and became visible again are failed.
+func (q *Queue) claim(ctx context.Context, queues []string, limit int, visibleAt time.Time) ([]Job, error) {
+	if err := q.failAbandoned(ctx, queues); err != nil {
+		return nil, err
+	}
+
+	table := pgx.Identifier(strings.Split(q.table, ".")).Sanitize()
+	query := "UPDATE " + table + " SET run_at = ?, attempts = attempts + 1 WHERE id IN (" +
+		"SELECT id FROM " + table + " WHERE queue IN ? AND failed_at IS NULL AND run_at <= ? " +
+		"AND attempts < max_attempts ORDER BY run_at LIMIT ? FOR UPDATE SKIP LOCKED) RETURNING *"
+
+	var jobs []Job
+
+	err := q.db.WithContext(ctx).Raw(query, visibleAt, queues, time.Now(), limit).Scan(&jobs).Error
+
+	return jobs, ActualError(err)
+}
+
+// failAbandoned fails due jobs without attempts left, their worker died during the last attempt.
+func (q *Queue) failAbandoned(ctx context.Context, queues []string) error {
+	now := time.Now()
+
+	err := q.db.WithContext(ctx).Table(q.table).
+		Where("queue IN ? AND failed_at IS NULL AND run_at <= ? AND attempts >= max_attempts", queues, now).
+		Updates(map[string]any{
+			"last_error": errJobAbandoned.Error(),
+			"failed_at":  now,
+		}).Error
+
+	return ActualError(err)
+}
+
+// owned limits query to the job if it was not claimed by anyone else since.
+func (q *Queue) owned(ctx context.Context, job *Job) *gorm.DB {
+	return q.db.WithContext(ctx).Table(q.table).Where("id = ? AND attempts = ?", job.ID, job.Attempts)
+}
+
+func (q *Queue) complete(ctx context.Context, job *Job) error {
+	return ActualError(q.owned(ctx, job).Delete(&Job{}).Error)
+}
+
+func (q *Queue) fail(ctx context.Context, job *Job, jobErr error, retryAt time.Time) error {
+	updates := map[string]any{
+		"last_error": jobErr.Error(),
+		"run_at":     retryAt,
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		updates["failed_at"] = time.Now()
+	}
+
+	return ActualError(q.owned(ctx, job).Updates(updates).Error)
+}
+
+// release makes the job visible right away without counting the attempt.
+func (q *Queue) release(ctx context.Context, job *Job) error {
+	err := q.owned(ctx, job).Updates(map[string]any{
+		"run_at":   time.Now(),
+		"attempts": gorm.Expr("attempts - 1"),
+	}).Error
+
+	return ActualError(err)
+}
+
+func (q *Queue) extend(ctx context.Context, job *Job, visibleAt time.Time) error {
+	return ActualError(q.owned(ctx, job).Update("run_at", visibleAt).Error)
+}
+
+func WithJobsTable(table string) QueueOpt {
+	return func(q *Queue) {
+		q.table = table
+	}
+}
+
+func WithJobQueue(queue string) EnqueueOpt {
+	return func(j *Job) {
+		j.Queue = queue
+	}
+}
+
+func WithRunAt(t time.Time) EnqueueOpt {
+	return func(j *Job) {
+		j.RunAt = t
+	}
+}
+
+func WithDelay(d time.Duration) EnqueueOpt {
+	return func(j *Job) {
+		j.RunAt = j.CreatedAt.Add(d)
+	}
+}
+
+func WithMaxAttempts(n int) EnqueueOpt {
+	return func(j *Job) {
+		j.MaxAttempts = max(n, 1)
+	}
+}
+
+// WithUniqueKey prevents enqueueing the job while another one with the same key
+// is pending or running, Enqueue returns ErrDuplicateJob in that case.
+func WithUniqueKey(key string) EnqueueOpt {
+	return func(j *Job) {
+		j.UniqueKey = &key
+	}
+}
+
+type JobHandler func(ctx context.Context, job *Job) error
+
+type WorkerPoolOpt func(*WorkerPool)
+
+func NewWorkerPool(queue *Queue, log logger.Logger, opts ...WorkerPoolOpt) *WorkerPool {
+	p := &WorkerPool{
+		queue:        queue,
+		logger:       log,
+		handlers:     make(map[string]JobHandler),
+		queues:       []string{DefaultQueue},
+		workers:      defaultJobWorkers,
+		pollInterval: defaultJobPollInterval,
+		visibility:   defaultJobVisibility,
+		backoff:      defaultJobBackoff,
+		maxBackoff:   defaultJobMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+type WorkerPool struct {
+	queue        *Queue
+	logger       logger.Logger
+	queues       []string
+	workers      int
+	pollInterval time.Duration
+	visibility   time.Duration
+	backoff      time.Duration
+	maxBackoff   time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+}
+
+func (p *WorkerPool) Handle(kind string, h JobHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[kind] = h
+}
+
+// Run processes jobs until ctx is cancelled. Jobs interrupted by cancellation
+// are released to be picked up again.
+func (p *WorkerPool) Run(ctx context.Context) error {
+	p.logger.Infof("job workers started: %d", p.workers)
+	defer p.logger.Infof("job workers done")
+
+	var wg sync.WaitGroup
+
+	for range p.workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			p.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (p *WorkerPool) work(ctx context.Context) {
+	for {
+		jobs, err := p.queue.claim(ctx, p.queues, 1, time.Now().Add(p.visibility))
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			p.logger.WithError(err).Errorf("claim job failed")
+		case len(jobs) > 0:
+			p.process(ctx, &jobs[0])
+
+			continue
+		}
+
+		if err := sleepContext(ctx, p.pollInterval); err != nil {
+			return
+		}
+	}
+}
+
+func (p *WorkerPool) process(ctx context.Context, job *Job) {
+	log := p.logger.
+		With("job_id", job.ID).
+		With("kind", job.Kind).
+		With("attempt", job.Attempts)
+
+	log.Debugf("job started")
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	stopRenew := p.renew(jobCtx, job, log)
+
+	jobErr := p.run(jobCtx, job)
+
+	stopRenew()
+	cancel()
+
+	// job is finalized even if worker is shutting down
+	finCtx, finCancel := context.WithTimeout(context.WithoutCancel(ctx), jobReleaseTimeout)
+	defer finCancel()
+
+	var err error
+
+	switch {
+	case jobErr == nil:
+		err = p.queue.complete(finCtx, job)
+
+		log.Debugf("job completed")
+	case ctx.Err() != nil:
+		err = p.queue.release(finCtx, job)
+
+		log.Infof("job interrupted by shutdown, released")
+	case job.Attempts >= job.MaxAttempts:
+		err = p.queue.fail(finCtx, job, jobErr, time.Now())
+
+		log.WithError(jobErr).Errorf("job failed permanently")
+	default:
+		err = p.queue.fail(finCtx, job, jobErr, time.Now().Add(backoff(job.Attempts-1, p.backoff, p.maxBackoff)))
+
+		log.WithError(jobErr).Warnf("job failed, will be retried")
+	}
+
+	if err != nil {
+		log.WithError(err).Errorf("job state update failed")
+	}
+}
+
+func (p *WorkerPool) run(ctx context.Context, job *Job) (err error) { // nolint: nonamedreturns
+	p.mu.RLock()
+	handler, ok := p.handlers[job.Kind]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoJobHandler, job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v: %s", r, debug.Stack()) // nolint: err113
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// renew keeps extending job visibility while it runs.
+func (p *WorkerPool) renew(ctx context.Context, job *Job, log logger.Logger) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(p.visibility / jobVisibilityRenewFactor)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			err := p.queue.extend(ctx, job, time.Now().Add(p.visibility))
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.WithError(err).Warnf("job visibility renew failed")
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func WithWorkers(n int) WorkerPoolOpt {
+	return func(p *WorkerPool) {
+		p.workers = max(n, 1)
+	}
+}
+
+func WithWorkerQueues(queues ...string) WorkerPoolOpt {
+	return func(p *WorkerPool) {
+		p.queues = queues
+	}
+}
+
+func WithWorkerPollInterval(interval time.Duration) WorkerPoolOpt {
+	return func(p *WorkerPool) {
+		p.pollInterval = interval
+	}
+}
+
+// WithVisibilityTimeout sets for how long the claimed job is hidden from other workers.
+// It is extended while the job runs, so it only matters if the worker dies. It is at least a second.
+func WithVisibilityTimeout(d time.Duration) WorkerPoolOpt {
+	return func(p *WorkerPool) {
+		p.visibility = max(d, minJobVisibility)
+	}
+}
+
+func WithJobBackoff(backoff, maxBackoff time.Duration) WorkerPoolOpt {
+	return func(p *WorkerPool) {
+		p.backoff = backoff
+		p.maxBackoff = maxBackoff
+	}
+}
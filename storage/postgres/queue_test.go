@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+func TestQueueEnqueue(t *testing.T) {
+	db := dryRunDB(t)
+	queue := NewQueue(db)
+
+	var sql string
+
+	err := db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	require.NoError(t, err)
+
+	t.Run("delayed", func(t *testing.T) {
+		job, err := queue.Enqueue(context.Background(), "send_email", map[string]string{"to": "bob"},
+			WithDelay(time.Minute),
+			WithMaxAttempts(3),
+			WithJobQueue("emails"),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "emails", job.Queue)
+		require.Equal(t, 3, job.MaxAttempts)
+		require.Equal(t, job.CreatedAt.Add(time.Minute), job.RunAt)
+		require.JSONEq(t, `{"to":"bob"}`, string(job.Payload))
+		require.NotContains(t, sql, "ON CONFLICT")
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		// dry run never inserts, so the job is reported as duplicate
+		_, err := queue.Enqueue(context.Background(), "report", nil, WithUniqueKey("daily-report"))
+		require.ErrorIs(t, err, ErrDuplicateJob)
+		require.Contains(t, sql, `ON CONFLICT ("unique_key")`)
+		require.Contains(t, sql, `WHERE failed_at IS NULL DO NOTHING`)
+	})
+}
+
+func TestWorkerPoolRun(t *testing.T) {
+	pool := NewWorkerPool(NewQueue(dryRunDB(t)), logger.NewNullLogger(),
+		WithWorkers(3),
+		WithWorkerPollInterval(time.Millisecond),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, pool.Run(ctx))
+}
+
+func TestQueueClaim(t *testing.T) {
+	db := dryRunDB(t)
+	statements := recordStatements(t, db)
+
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		*statements = append(*statements, tx.Statement.SQL.String())
+	}))
+
+	// dry run can't scan rows, the statement is recorded anyway
+	_, err := NewQueue(db, WithJobsTable("tenant.jobs")).claim(context.Background(), []string{DefaultQueue}, 1, time.Now())
+	require.ErrorIs(t, err, gorm.ErrDryRunModeUnsupported)
+	require.Len(t, *statements, 2)
+
+	// jobs of workers died on the last attempt are failed instead of being claimed again
+	require.Contains(t, (*statements)[0], `UPDATE "tenant"."jobs" SET "failed_at"=$1,"last_error"=$2`)
+	require.Contains(t, (*statements)[0], `attempts >= max_attempts`)
+
+	require.Contains(t, (*statements)[1], `UPDATE "tenant"."jobs" SET`)
+	require.Contains(t, (*statements)[1], `SELECT id FROM "tenant"."jobs"`)
+	require.Contains(t, (*statements)[1], `AND attempts < max_attempts`)
+}
+
+func TestWithVisibilityTimeout(t *testing.T) {
+	pool := NewWorkerPool(NewQueue(dryRunDB(t)), logger.NewNullLogger(), WithVisibilityTimeout(0))
+	require.Equal(t, minJobVisibility, pool.visibility)
+
+	pool = NewWorkerPool(NewQueue(dryRunDB(t)), logger.NewNullLogger(), WithVisibilityTimeout(time.Minute))
+	require.Equal(t, time.Minute, pool.visibility)
+}
@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	replicasPluginName = "wheel:replicas"
+
+	defaultReplicaCheckInterval = 5 * time.Second
+	defaultReplicaMaxFailures   = 3
+)
+
+type ReplicaPolicy uint8
+
+const (
+	RoundRobin ReplicaPolicy = iota
+	LeastLatency
+)
+
+const usePrimarySetting = replicasPluginName + ":primary"
+
+var (
+	readQuery = regexp.MustCompile(`(?is)^\s*(SELECT|WITH)\b`)
+	// primaryQuery matches reads which lock rows (FOR UPDATE, FOR SHARE) or contain writes (WITH ... INSERT)
+	primaryQuery = regexp.MustCompile(`(?i)\b(UPDATE|SHARE|INSERT|DELETE|MERGE)\b`)
+)
+
+type (
+	usePrimaryKey struct{}
+	stickyKey     struct{}
+	stickyPrimary struct{ written atomic.Bool }
+)
+
+// WithReplicas makes read queries outside of transactions and dedicated connections (db.Connection) go to
+// the replicas. Queries locking rows, writing in WITH clauses and flagged with OnPrimary go to the primary.
+func WithReplicas(dsns ...string) DBOption {
+	return func(config *dbConfig) {
+		config.replicas = append(config.replicas, dsns...)
+	}
+}
+
+func WithReplicaPolicy(policy ReplicaPolicy) DBOption {
+	return func(config *dbConfig) {
+		config.replicaPolicy = policy
+	}
+}
+
+// WithReplicaHealthCheck pings replicas every 'interval', replica is not used
+// after 'maxFailures' failed pings in a row until it responds again.
+func WithReplicaHealthCheck(interval time.Duration, maxFailures int) DBOption {
+	return func(config *dbConfig) {
+		config.replicaCheckInterval = interval
+		config.replicaMaxFailures = max(maxFailures, 1)
+	}
+}
+
+// UsePrimary returns context in which all queries go to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+// OnPrimary is a scope making the statement go to the primary, e.g. for queries calling
+// functions with side effects or reading own writes.
+//
+//	db.Scopes(postgres.OnPrimary).Raw("SELECT nextval('ids')").Scan(&id)
+func OnPrimary(db *gorm.DB) *gorm.DB {
+	return db.Set(usePrimarySetting, true)
+}
+
+// WithStickyPrimary returns context in which queries go to the primary after the first write
+// made with it, so e.g. a request reads its own writes despite replication lag.
+func WithStickyPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyKey{}, &stickyPrimary{})
+}
+
+// ReadOnlyTransaction runs 'fn' in a read only transaction on a replica, or the primary if there are none available.
+func ReadOnlyTransaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return Transaction(ctx, db, fn)
+	}
+
+	tx := db.Session(&gorm.Session{Context: ctx})
+
+	if r, ok := db.Plugins[replicasPluginName].(*replicaRouter); ok && !usePrimary(ctx) {
+		if replica := r.pick(); replica != nil {
+			tx.Statement.ConnPool = replica.pool
+		}
+	}
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		ctx, err := applyTenant(context.WithValue(ctx, txKey{}, tx), tx)
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	}, &sql.TxOptions{ReadOnly: true})
+
+	return ActualError(err)
+}
+
+// Close closes connection pools of the database and its replicas.
+func Close(db *gorm.DB) error {
+	var errs []error
+
+	if r, ok := db.Plugins[replicasPluginName].(*replicaRouter); ok {
+		errs = append(errs, r.close())
+	}
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+
+	if err := errors.Join(append(errs, err)...); err != nil {
+		return fmt.Errorf("postgres: close: %w", err)
+	}
+
+	return nil
+}
+
+func openReplicas(cfg *dbConfig) (*replicaRouter, error) {
+	replicas := make([]*replica, 0, len(cfg.replicas))
+
+	for _, dsn := range cfg.replicas {
+		pool, err := openPool(dsn, cfg)
+		if err != nil {
+			for _, r := range replicas {
+				_ = r.pool.Close()
+			}
+
+			return nil, err
+		}
+
+		replicas = append(replicas, newReplica(pool))
+	}
+
+	return newReplicaRouter(replicas, cfg.replicaPolicy, cfg.replicaCheckInterval, cfg.replicaMaxFailures), nil
+}
+
+func newReplica(pool *sql.DB) *replica {
+	r := &replica{pool: pool}
+	r.healthy.Store(true)
+
+	return r
+}
+
+type replica struct {
+	pool     *sql.DB
+	healthy  atomic.Bool
+	latency  atomic.Int64
+	failures int
+}
+
+func newReplicaRouter(replicas []*replica, policy ReplicaPolicy, interval time.Duration, maxFailures int) *replicaRouter {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &replicaRouter{
+		replicas:    replicas,
+		policy:      policy,
+		interval:    cmp.Or(interval, defaultReplicaCheckInterval),
+		maxFailures: cmp.Or(maxFailures, defaultReplicaMaxFailures),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+type replicaRouter struct {
+	primary     gorm.ConnPool
+	replicas    []*replica
+	policy      ReplicaPolicy
+	interval    time.Duration
+	maxFailures int
+	next        atomic.Uint64
+
+	ctx    context.Context // nolint: containedctx
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (r *replicaRouter) Name() string {
+	return replicasPluginName
+}
+
+func (r *replicaRouter) Initialize(db *gorm.DB) error {
+	name := replicasPluginName + ":route"
+	r.primary = db.ConnPool
+
+	err := errors.Join(
+		db.Callback().Query().Before("gorm:query").Register(name, r.route),
+		db.Callback().Row().Before("gorm:row").Register(name, r.route),
+		db.Callback().Create().After("gorm:create").Register(name, r.written),
+		db.Callback().Update().After("gorm:update").Register(name, r.written),
+		db.Callback().Delete().After("gorm:delete").Register(name, r.written),
+		db.Callback().Raw().After("gorm:raw").Register(name, r.written),
+	)
+	if err != nil {
+		return fmt.Errorf("register replica callbacks: %w", err)
+	}
+
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		r.healthCheck()
+	}()
+
+	return nil
+}
+
+func (r *replicaRouter) route(db *gorm.DB) {
+	stmt := db.Statement
+
+	// statements of transactions and dedicated connections (db.Connection) stay on them
+	if stmt.ConnPool != r.primary || usePrimary(stmt.Context) {
+		return
+	}
+
+	if _, primary := db.Get(usePrimarySetting); primary {
+		return
+	}
+
+	if _, locking := stmt.Clauses["FOR"]; locking {
+		return
+	}
+
+	if stmt.SQL.Len() > 0 && !replicaRead(stmt.SQL.String()) {
+		return
+	}
+
+	if replica := r.pick(); replica != nil {
+		stmt.ConnPool = replica.pool
+	}
+}
+
+func (r *replicaRouter) written(db *gorm.DB) {
+	if replicaRead(db.Statement.SQL.String()) {
+		return
+	}
+
+	if sticky, ok := db.Statement.Context.Value(stickyKey{}).(*stickyPrimary); ok && db.Error == nil {
+		sticky.written.Store(true)
+	}
+}
+
+// pick returns healthy replica according to the policy or nil if there are none.
+func (r *replicaRouter) pick() *replica {
+	var best *replica
+
+	switch r.policy {
+	case RoundRobin:
+		start := r.next.Add(1) - 1
+
+		for i := range uint64(len(r.replicas)) {
+			replica := r.replicas[(start+i)%uint64(len(r.replicas))]
+			if replica.healthy.Load() {
+				return replica
+			}
+		}
+	case LeastLatency:
+		for _, replica := range r.replicas {
+			if replica.healthy.Load() && (best == nil || replica.latency.Load() < best.latency.Load()) {
+				best = replica
+			}
+		}
+	}
+
+	return best
+}
+
+func (r *replicaRouter) healthCheck() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkReplicas(r.ctx)
+		}
+	}
+}
+
+func (r *replicaRouter) checkReplicas(ctx context.Context) {
+	for _, replica := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, r.interval)
+		start := time.Now()
+		err := replica.pool.PingContext(pingCtx)
+
+		cancel()
+
+		if err != nil {
+			replica.failures++
+
+			if replica.failures >= r.maxFailures {
+				replica.healthy.Store(false)
+			}
+
+			continue
+		}
+
+		replica.failures = 0
+		replica.latency.Store(int64(time.Since(start)))
+		replica.healthy.Store(true)
+	}
+}
+
+func (r *replicaRouter) close() error {
+	r.cancel()
+	r.wg.Wait()
+
+	errs := make([]error, 0, len(r.replicas))
+
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.pool.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// replicaRead reports whether raw 'sql' only reads, so it can run on a replica.
+func replicaRead(sql string) bool {
+	return readQuery.MatchString(sql) && !primaryQuery.MatchString(sql)
+}
+
+func usePrimary(ctx context.Context) bool {
+	if primary, _ := ctx.Value(usePrimaryKey{}).(bool); primary {
+		return true
+	}
+
+	sticky, ok := ctx.Value(stickyKey{}).(*stickyPrimary)
+
+	return ok && sticky.written.Load()
+}
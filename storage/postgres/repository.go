@@ -0,0 +1,447 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+var _ storage.Repository[struct{}] = (*Repository[struct{}])(nil)
+
+type RepositoryOpt func(*repositoryConfig)
+
+type repositoryConfig struct {
+	conflictColumns []string
+}
+
+// NewRepository creates a repository for the model 'T' with a single primary key.
+func NewRepository[T any](db *gorm.DB, opts ...RepositoryOpt) (*Repository[T], error) {
+	var cfg repositoryConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("postgres: parse model: %w", err)
+	}
+
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("postgres: model %s has no primary key", stmt.Schema.Name) // nolint: err113
+	}
+
+	conflict := make([]clause.Column, 0, len(cfg.conflictColumns))
+
+	for _, name := range cfg.conflictColumns {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, fmt.Errorf("postgres: model %s has no field %q", stmt.Schema.Name, name) // nolint: err113
+		}
+
+		conflict = append(conflict, clause.Column{Name: field.DBName})
+	}
+
+	if len(conflict) == 0 {
+		conflict = append(conflict, clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName})
+	}
+
+	return &Repository[T]{
+		db:       db,
+		schema:   stmt.Schema,
+		conflict: conflict,
+	}, nil
+}
+
+type Repository[T any] struct {
+	db       *gorm.DB
+	schema   *schema.Schema
+	conflict []clause.Column
+}
+
+func (r *Repository[T]) Get(ctx context.Context, id any, opts ...storage.QueryOption) (*T, error) {
+	query := storage.NewQuery(opts...)
+	query.Filters = append(query.Filters, storage.Eq(r.schema.PrioritizedPrimaryField.DBName, id))
+
+	tx, err := r.query(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	var entity T
+
+	if err := tx.Take(&entity).Error; err != nil {
+		return nil, ActualError(err)
+	}
+
+	return &entity, nil
+}
+
+func (r *Repository[T]) List(ctx context.Context, opts ...storage.QueryOption) ([]T, error) {
+	query := storage.NewQuery(opts...)
+
+	return r.list(ctx, &query)
+}
+
+// Page returns at most Limit records. Primary key is always added as the last
+// sort field, so the cursor stays unique.
+func (r *Repository[T]) Page(ctx context.Context, opts ...storage.QueryOption) (storage.Page[T], error) {
+	query := storage.NewQuery(opts...)
+	query.SortedBy(r.schema.PrioritizedPrimaryField.DBName)
+
+	limit := query.Limit
+	if limit > 0 {
+		query.Limit++
+	}
+
+	items, err := r.list(ctx, &query)
+	if err != nil {
+		return storage.Page[T]{}, err
+	}
+
+	if limit == 0 || len(items) <= limit {
+		return storage.Page[T]{Items: items}, nil
+	}
+
+	items = items[:limit]
+
+	next, err := r.cursor(ctx, &items[limit-1], query.Sort)
+	if err != nil {
+		return storage.Page[T]{}, err
+	}
+
+	return storage.Page[T]{Items: items, Next: next}, nil
+}
+
+func (r *Repository[T]) Count(ctx context.Context, opts ...storage.QueryOption) (int64, error) {
+	query := storage.NewQuery(opts...)
+	query.Sort, query.Limit, query.Offset, query.After = nil, 0, 0, nil
+
+	tx, err := r.query(ctx, &query)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+
+	if err := tx.Count(&count).Error; err != nil {
+		return 0, ActualError(err)
+	}
+
+	return count, nil
+}
+
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	if versioned, ok := any(entity).(storage.VersionedRecord); ok && versioned.CurrentVersion() == 0 {
+		versioned.SetVersion(1)
+	}
+
+	return ActualError(Conn(ctx, r.db).Create(entity).Error)
+}
+
+// Update saves all fields of 'entity' except the creation timestamps.
+// Entities implementing storage.VersionedRecord are only updated if the stored
+// version matches, otherwise storage.ErrStaleRecord is returned.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	tx := Conn(ctx, r.db).Model(entity)
+
+	versioned, isVersioned := any(entity).(storage.VersionedRecord)
+	if !isVersioned {
+		res := tx.Select("*").Omit(r.createTimeFields()...).Updates(entity)
+		if res.Error != nil {
+			return ActualError(res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return storage.ErrRecordNotFound
+		}
+
+		return nil
+	}
+
+	field, err := r.field(storage.VersionField)
+	if err != nil {
+		return err
+	}
+
+	current := versioned.CurrentVersion()
+	versioned.SetVersion(current + 1)
+
+	res := tx.Where(clause.Eq{Column: r.column(field), Value: current}).
+		Select("*").Omit(r.createTimeFields()...).Updates(entity)
+	if res.Error != nil || res.RowsAffected == 0 {
+		versioned.SetVersion(current)
+	}
+
+	if res.Error != nil {
+		return ActualError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return storage.ErrStaleRecord
+	}
+
+	return nil
+}
+
+// Upsert inserts 'entity' or updates all its fields on primary key (or configured columns) conflict.
+// Entities implementing storage.VersionedRecord are only updated if the stored version matches,
+// as with Update, and new ones (version 0) only inserted, otherwise storage.ErrStaleRecord is returned.
+func (r *Repository[T]) Upsert(ctx context.Context, entity *T) error {
+	onConflict := clause.OnConflict{Columns: r.conflict, UpdateAll: true}
+
+	versioned, isVersioned := any(entity).(storage.VersionedRecord)
+	if !isVersioned {
+		return ActualError(Conn(ctx, r.db).Clauses(onConflict).Create(entity).Error)
+	}
+
+	field, err := r.field(storage.VersionField)
+	if err != nil {
+		return err
+	}
+
+	current := versioned.CurrentVersion()
+	versioned.SetVersion(current + 1)
+
+	onConflict.Where = clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: r.schema.Table, Name: field.DBName}, Value: current},
+	}}
+
+	res := Conn(ctx, r.db).Clauses(onConflict).Create(entity)
+	if res.Error != nil || res.RowsAffected == 0 {
+		versioned.SetVersion(current)
+	}
+
+	if res.Error != nil {
+		return ActualError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return storage.ErrStaleRecord
+	}
+
+	return nil
+}
+
+// Delete removes the record, soft deleting it when the model supports that.
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	res := Conn(ctx, r.db).
+		Where(clause.Eq{Column: r.column(r.schema.PrioritizedPrimaryField), Value: id}).
+		Delete(new(T))
+	if res.Error != nil {
+		return ActualError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return storage.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) list(ctx context.Context, query *storage.Query) ([]T, error) {
+	tx, err := r.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+
+	if err := tx.Find(&items).Error; err != nil {
+		return nil, ActualError(err)
+	}
+
+	return items, nil
+}
+
+func (r *Repository[T]) query(ctx context.Context, query *storage.Query) (*gorm.DB, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx := Conn(ctx, r.db).Model(new(T))
+
+	if query.WithDeleted {
+		tx = tx.Unscoped()
+	}
+
+	for _, filter := range query.Filters {
+		expr, err := r.filter(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		tx = tx.Where(expr)
+	}
+
+	if len(query.After) > 0 {
+		expr, err := r.keyset(query.Sort, query.After)
+		if err != nil {
+			return nil, err
+		}
+
+		tx = tx.Where(expr)
+	}
+
+	for _, s := range query.Sort {
+		field, err := r.field(s.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		tx = tx.Order(clause.OrderByColumn{Column: r.column(field), Desc: s.Desc})
+	}
+
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+
+	if query.Offset > 0 {
+		tx = tx.Offset(query.Offset)
+	}
+
+	return tx, nil
+}
+
+func (r *Repository[T]) filter(filter storage.Filter) (clause.Expression, error) {
+	field, err := r.field(filter.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	column := r.column(field)
+
+	switch filter.Op {
+	case storage.OpEq:
+		return clause.Eq{Column: column, Value: filter.Value}, nil
+	case storage.OpNotEq:
+		return clause.Neq{Column: column, Value: filter.Value}, nil
+	case storage.OpLt:
+		return clause.Lt{Column: column, Value: filter.Value}, nil
+	case storage.OpLte:
+		return clause.Lte{Column: column, Value: filter.Value}, nil
+	case storage.OpGt:
+		return clause.Gt{Column: column, Value: filter.Value}, nil
+	case storage.OpGte:
+		return clause.Gte{Column: column, Value: filter.Value}, nil
+	case storage.OpLike:
+		return clause.Like{Column: column, Value: filter.Value}, nil
+	case storage.OpIsNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case storage.OpNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	case storage.OpIn, storage.OpNotIn:
+		values, err := sliceValues(filter.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Op == storage.OpNotIn {
+			return clause.Not(clause.IN{Column: column, Values: values}), nil
+		}
+
+		return clause.IN{Column: column, Values: values}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operator %q", storage.ErrInvalidQuery, filter.Op)
+}
+
+// keyset builds '(a > x) OR (a = x AND b > y) OR ...' condition for the sort fields.
+func (r *Repository[T]) keyset(sort []storage.Sort, after []any) (clause.Expression, error) {
+	alternatives := make([]clause.Expression, 0, len(sort))
+
+	for i, s := range sort {
+		conditions := make([]clause.Expression, 0, i+1)
+
+		for j := range i {
+			expr, err := r.filter(storage.Eq(sort[j].Field, after[j]))
+			if err != nil {
+				return nil, err
+			}
+
+			conditions = append(conditions, expr)
+		}
+
+		op := storage.Gt(s.Field, after[i])
+		if s.Desc {
+			op = storage.Lt(s.Field, after[i])
+		}
+
+		expr, err := r.filter(op)
+		if err != nil {
+			return nil, err
+		}
+
+		alternatives = append(alternatives, clause.And(append(conditions, expr)...))
+	}
+
+	return clause.Or(alternatives...), nil
+}
+
+func (r *Repository[T]) cursor(ctx context.Context, entity *T, sort []storage.Sort) ([]any, error) {
+	value := reflect.ValueOf(entity).Elem()
+	cursor := make([]any, 0, len(sort))
+
+	for _, s := range sort {
+		field, err := r.field(s.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		v, _ := field.ValueOf(ctx, value)
+		cursor = append(cursor, v)
+	}
+
+	return cursor, nil
+}
+
+func (r *Repository[T]) field(name string) (*schema.Field, error) {
+	field := r.schema.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("%w: unknown field %q", storage.ErrInvalidQuery, name)
+	}
+
+	return field, nil
+}
+
+func (r *Repository[T]) column(field *schema.Field) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+}
+
+func (r *Repository[T]) createTimeFields() []string {
+	var fields []string
+
+	for _, field := range r.schema.Fields {
+		if field.AutoCreateTime > 0 {
+			fields = append(fields, field.DBName)
+		}
+	}
+
+	return fields
+}
+
+func WithConflictColumns(columns ...string) RepositoryOpt {
+	return func(cfg *repositoryConfig) {
+		cfg.conflictColumns = columns
+	}
+}
+
+func sliceValues(v any) ([]any, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: expected slice, got %T", storage.ErrInvalidQuery, v)
+	}
+
+	values := make([]any, value.Len())
+	for i := range values {
+		values[i] = value.Index(i).Interface()
+	}
+
+	return values, nil
+}
@@ -0,0 +1,296 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrNoTenant               = wherr.Error("no tenant in context")
+	ErrTenantNotInTransaction = wherr.Error("tenant schema is only set in transaction")
+
+	tenancyPluginName   = "wheel:tenancy"
+	defaultTenantColumn = "tenant_id"
+)
+
+type TenantIsolation uint8
+
+const (
+	// SchemaIsolation keeps every tenant in its own schema, selected with
+	// 'SET LOCAL search_path' at the start of Transaction.
+	SchemaIsolation TenantIsolation = iota
+	// RowIsolation keeps tenants in shared tables scoped by the tenant column.
+	RowIsolation
+)
+
+type (
+	tenantScopeKey struct{}
+	noTenantKey    struct{}
+)
+
+// tenantScope is the transaction whose search path selects the schema.
+type tenantScope struct {
+	schema string
+	conn   gorm.ConnPool
+}
+
+type TenancyOpt func(*Tenancy)
+
+// NewTenancy creates gorm plugin isolating tenants taken from whctx.TenantID.
+// Statements run without tenant fail with ErrNoTenant, unless they touch shared
+// tables or run with WithoutTenant context. With SchemaIsolation statements must
+// run inside Transaction, which sets the search path of the tenant, on its transaction
+// taken with Conn: db.WithContext(ctx) would use another connection of the pool.
+//
+//	db.Use(postgres.NewTenancy(postgres.RowIsolation, postgres.WithSharedTables("plans")))
+func NewTenancy(isolation TenantIsolation, opts ...TenancyOpt) *Tenancy {
+	t := &Tenancy{
+		isolation: isolation,
+		column:    defaultTenantColumn,
+		schema:    func(tenantID string) string { return tenantID },
+		shared:    make(map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+type Tenancy struct {
+	isolation TenantIsolation
+	column    string
+	schema    func(tenantID string) string
+	shared    map[string]struct{}
+}
+
+func (t *Tenancy) Name() string {
+	return tenancyPluginName
+}
+
+func (t *Tenancy) Initialize(db *gorm.DB) error {
+	name := tenancyPluginName + ":scope"
+
+	err := errors.Join(
+		db.Callback().Create().Before("gorm:create").Register(name, t.create),
+		db.Callback().Query().Before("gorm:query").Register(name, t.scope),
+		db.Callback().Update().Before("gorm:update").Register(name, t.scopeWrite),
+		db.Callback().Delete().Before("gorm:delete").Register(name, t.scopeWrite),
+		db.Callback().Row().Before("gorm:row").Register(name, t.scope),
+		db.Callback().Raw().Before("gorm:raw").Register(name, t.guard),
+	)
+	if err != nil {
+		return fmt.Errorf("register tenancy callbacks: %w", err)
+	}
+
+	return nil
+}
+
+// WithoutTenant returns context in which statements are not checked nor scoped, e.g. for migrations.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTenantKey{}, true)
+}
+
+// tenant returns the tenant of the statement, adding an error if it is required but missing.
+func (t *Tenancy) tenant(db *gorm.DB) (string, bool) {
+	ctx := db.Statement.Context
+
+	if skip, _ := ctx.Value(noTenantKey{}).(bool); skip {
+		return "", false
+	}
+
+	if _, ok := t.shared[db.Statement.Table]; ok && db.Statement.Table != "" {
+		return "", false
+	}
+
+	tenantID := whctx.TenantID(ctx)
+	if tenantID == "" {
+		_ = db.AddError(ErrNoTenant)
+
+		return "", false
+	}
+
+	if t.isolation == SchemaIsolation {
+		// context of the transaction may be used with another connection, e.g. db.WithContext(ctx)
+		if !inTenantScope(ctx, t.schema(tenantID), db.Statement.ConnPool) {
+			_ = db.AddError(ErrTenantNotInTransaction)
+
+			return "", false
+		}
+
+		// search path already selects the tenant
+		return "", false
+	}
+
+	return tenantID, true
+}
+
+func (t *Tenancy) guard(db *gorm.DB) {
+	t.tenant(db)
+}
+
+func (t *Tenancy) scope(db *gorm.DB) {
+	tenantID, ok := t.tenant(db)
+	if !ok || db.Statement.Schema == nil || db.Statement.Schema.LookUpField(t.column) == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: t.column}, Value: tenantID},
+	}})
+}
+
+func (t *Tenancy) scopeWrite(db *gorm.DB) {
+	// gorm adds primary key conditions after this callback and refuses writes without any,
+	// the tenant scope must not make a global write pass that check
+	if _, ok := db.Statement.Clauses["WHERE"]; !ok && !db.AllowGlobalUpdate && !t.hasPrimaryKey(db) {
+		t.guard(db)
+
+		return
+	}
+
+	t.scope(db)
+}
+
+func (t *Tenancy) create(db *gorm.DB) {
+	tenantID, ok := t.tenant(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(t.column)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	value := db.Statement.ReflectValue
+
+	switch value.Kind() { // nolint: exhaustive
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			_ = db.AddError(field.Set(ctx, reflect.Indirect(value.Index(i)), tenantID))
+		}
+	case reflect.Struct:
+		_ = db.AddError(field.Set(ctx, value, tenantID))
+	}
+
+	// upsert must not update the conflicting row of another tenant
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, clause.Eq{
+				Column: clause.Column{Table: db.Statement.Table, Name: field.DBName},
+				Value:  tenantID,
+			})
+			db.Statement.AddClause(onConflict)
+		}
+	}
+}
+
+func (t *Tenancy) hasPrimaryKey(db *gorm.DB) bool {
+	if db.Statement.Schema == nil {
+		return false
+	}
+
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return false
+	}
+
+	for _, value := range structValues(db.Statement.ReflectValue) {
+		if _, zero := field.ValueOf(db.Statement.Context, value); !zero {
+			return true
+		}
+	}
+
+	return false
+}
+
+// structValues returns 'value' if it is a struct or its struct elements if it is a slice or an array.
+func structValues(value reflect.Value) []reflect.Value {
+	value = reflect.Indirect(value)
+
+	switch value.Kind() { // nolint: exhaustive
+	case reflect.Struct:
+		return []reflect.Value{value}
+	case reflect.Slice, reflect.Array:
+		values := make([]reflect.Value, 0, value.Len())
+
+		for i := range value.Len() {
+			if elem := reflect.Indirect(value.Index(i)); elem.Kind() == reflect.Struct {
+				values = append(values, elem)
+			}
+		}
+
+		return values
+	}
+
+	return nil
+}
+
+// applyTenant sets search path of the transaction to the schema of the tenant from ctx.
+func applyTenant(ctx context.Context, tx *gorm.DB) (context.Context, error) {
+	t, ok := tx.Plugins[tenancyPluginName].(*Tenancy)
+	if !ok || t.isolation != SchemaIsolation {
+		return ctx, nil
+	}
+
+	tenantID := whctx.TenantID(ctx)
+	if tenantID == "" {
+		return ctx, nil
+	}
+
+	schema := t.schema(tenantID)
+	if inTenantScope(ctx, schema, tx.Statement.ConnPool) {
+		return ctx, nil
+	}
+
+	// executed directly on the connection, so tenancy callbacks don't see it
+	_, err := tx.Statement.ConnPool.ExecContext(ctx, "SELECT set_config('search_path', $1, true)",
+		pgx.Identifier{schema}.Sanitize()+", public")
+	if err != nil {
+		return nil, fmt.Errorf("set tenant search path: %w", err)
+	}
+
+	return context.WithValue(ctx, tenantScopeKey{}, &tenantScope{schema: schema, conn: tx.Statement.ConnPool}), nil
+}
+
+// inTenantScope reports whether statements on 'conn' run with search path of 'schema'.
+func inTenantScope(ctx context.Context, schema string, conn gorm.ConnPool) bool {
+	scope, ok := ctx.Value(tenantScopeKey{}).(*tenantScope)
+
+	return ok && scope.schema == schema && scope.conn == conn
+}
+
+// WithTenantColumn sets the column scoped by RowIsolation, "tenant_id" by default.
+func WithTenantColumn(column string) TenancyOpt {
+	return func(t *Tenancy) {
+		t.column = column
+	}
+}
+
+// WithTenantSchema sets mapping of tenants to schemas used by SchemaIsolation, tenant ID itself by default.
+func WithTenantSchema(fn func(tenantID string) string) TenancyOpt {
+	return func(t *Tenancy) {
+		t.schema = fn
+	}
+}
+
+// WithSharedTables sets tables which are not tenant specific.
+func WithSharedTables(tables ...string) TenancyOpt {
+	return func(t *Tenancy) {
+		for _, table := range tables {
+			t.shared[table] = struct{}{}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+type testTenantUser struct {
+	ID       int64
+	TenantID string
+	Name     string
+}
+
+func TestTenancyRowIsolation(t *testing.T) {
+	db := dryRunDB(t)
+	require.NoError(t, db.Use(NewTenancy(RowIsolation, WithSharedTables("plans"))))
+
+	ctx := whctx.WithTenantID(context.Background(), "acme")
+
+	t.Run("query", func(t *testing.T) {
+		stmt := db.WithContext(ctx).Where("name = ?", "bob").Find(&[]testTenantUser{}).Statement
+		require.Contains(t, stmt.SQL.String(), `"test_tenant_users"."tenant_id" = $2`)
+		require.Equal(t, []any{"bob", "acme"}, stmt.Vars)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		user := testTenantUser{Name: "bob"}
+		require.NoError(t, db.WithContext(ctx).Create(&user).Error)
+		require.Equal(t, "acme", user.TenantID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		stmt := db.WithContext(ctx).Delete(&testTenantUser{ID: 1}).Statement
+		require.NoError(t, stmt.Error)
+		require.Equal(t, `DELETE FROM "test_tenant_users" `+
+			`WHERE "test_tenant_users"."tenant_id" = $1 AND "test_tenant_users"."id" = $2`, stmt.SQL.String())
+
+		stmt = db.WithContext(ctx).Delete(&[]testTenantUser{{ID: 1}, {ID: 2}}).Statement
+		require.NoError(t, stmt.Error)
+		require.Equal(t, `DELETE FROM "test_tenant_users" `+
+			`WHERE "test_tenant_users"."tenant_id" = $1 AND "test_tenant_users"."id" IN ($2,$3)`, stmt.SQL.String())
+		require.Equal(t, []any{"acme", int64(1), int64(2)}, stmt.Vars)
+
+		err := db.WithContext(ctx).Delete(&[]testTenantUser{{Name: "bob"}}).Error
+		require.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		stmt := db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(&testTenantUser{ID: 1, Name: "bob"}).Statement
+		require.NoError(t, stmt.Error)
+		require.Contains(t, stmt.SQL.String(), `ON CONFLICT ("id") DO UPDATE SET "tenant_id"="excluded"."tenant_id",`+
+			`"name"="excluded"."name" WHERE "test_tenant_users"."tenant_id" = $4`)
+		require.Equal(t, "acme", stmt.Vars[3])
+	})
+
+	t.Run("global delete", func(t *testing.T) {
+		err := db.WithContext(ctx).Delete(&testTenantUser{}).Error
+		require.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+	})
+
+	t.Run("no tenant", func(t *testing.T) {
+		err := db.WithContext(context.Background()).Find(&[]testTenantUser{}).Error
+		require.ErrorIs(t, err, ErrNoTenant)
+
+		err = db.WithContext(context.Background()).Exec("SELECT 1").Error
+		require.ErrorIs(t, err, ErrNoTenant)
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		require.NoError(t, db.WithContext(context.Background()).Table("plans").Find(&[]map[string]any{}).Error)
+		require.NoError(t, db.WithContext(WithoutTenant(context.Background())).Exec("SELECT 1").Error)
+	})
+}
+
+func TestTenancySchemaIsolation(t *testing.T) {
+	conns := &fakeTxConnector{}
+	sqlDB := sql.OpenDB(conns)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 gormlogger.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewTenancy(SchemaIsolation, WithTenantSchema(func(id string) string {
+		return "tenant_" + id
+	}))))
+
+	ctx := whctx.WithTenantID(context.Background(), "acme")
+
+	err = db.WithContext(ctx).Find(&[]testTenantUser{}).Error
+	require.ErrorIs(t, err, ErrTenantNotInTransaction)
+
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		stmt := Conn(ctx, db).Find(&[]testTenantUser{}).Statement
+		require.NoError(t, stmt.Error)
+		require.NotContains(t, stmt.SQL.String(), "tenant_id")
+
+		// nested transaction keeps the connection and its search path
+		require.NoError(t, Transaction(ctx, db, func(ctx context.Context) error {
+			return Conn(ctx, db).Find(&[]testTenantUser{}).Error
+		}))
+
+		// context of the transaction used with another connection of the pool
+		err := db.WithContext(ctx).Find(&[]testTenantUser{}).Error
+		require.ErrorIs(t, err, ErrTenantNotInTransaction)
+
+		err = db.WithContext(ctx).Exec("SELECT 1").Error
+		require.ErrorIs(t, err, ErrTenantNotInTransaction)
+
+		// tenant changed after the search path was set
+		err = Conn(whctx.WithTenantID(ctx, "other"), db).Find(&[]testTenantUser{}).Error
+		require.ErrorIs(t, err, ErrTenantNotInTransaction)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{`"tenant_acme", public`}, conns.searchPaths())
+}
+
+// fakeTxConnector accepts transactions and records search paths they set.
+type fakeTxConnector struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (c *fakeTxConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeTxConn{connector: c}, nil
+}
+
+func (c *fakeTxConnector) Driver() driver.Driver {
+	return fakeLockDriver{}
+}
+
+func (c *fakeTxConnector) searchPaths() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.paths)
+}
+
+type fakeTxConn struct {
+	connector *fakeTxConnector
+}
+
+func (c *fakeTxConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "search_path") {
+		return nil, errors.New("unexpected statement: " + query)
+	}
+
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+
+	path, _ := args[0].Value.(string)
+	c.connector.paths = append(c.connector.paths, path)
+
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeTxConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeTxConn) Close() error {
+	return nil
+}
+
+func (c *fakeTxConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeTxConn) Commit() error {
+	return nil
+}
+
+func (c *fakeTxConn) Rollback() error {
+	return nil
+}
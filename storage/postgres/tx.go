@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+type txKey struct{}
+
+var _ storage.Transactor = (*Transactor)(nil)
+
+func NewTransactor(db *gorm.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+type Transactor struct {
+	db *gorm.DB
+}
+
+func (t *Transactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Transaction(ctx, t.db, fn)
+}
+
+// Transaction runs 'fn' in a transaction stored in the context passed to it.
+// If ctx already carries a transaction, a nested one (savepoint) is used.
+// With SchemaIsolation tenancy the search path is set to the schema of the tenant.
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	err := Conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		ctx, err := applyTenant(context.WithValue(ctx, txKey{}, tx), tx)
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	})
+
+	return ActualError(err)
+}
+
+// Conn returns the transaction carried by ctx or 'db' otherwise, bound to ctx.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
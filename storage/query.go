@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Operator string
+
+const (
+	OpEq      Operator = "="
+	OpNotEq   Operator = "<>"
+	OpLt      Operator = "<"
+	OpLte     Operator = "<="
+	OpGt      Operator = ">"
+	OpGte     Operator = ">="
+	OpIn      Operator = "IN"
+	OpNotIn   Operator = "NOT IN"
+	OpLike    Operator = "LIKE"
+	OpIsNull  Operator = "IS NULL"
+	OpNotNull Operator = "IS NOT NULL"
+)
+
+// Filter is a single condition on the column 'Field'.
+// For OpIn and OpNotIn 'Value' must be a slice.
+type Filter struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+func Eq(field string, value any) Filter {
+	return Filter{Field: field, Op: OpEq, Value: value}
+}
+
+func NotEq(field string, value any) Filter {
+	return Filter{Field: field, Op: OpNotEq, Value: value}
+}
+
+func Lt(field string, value any) Filter {
+	return Filter{Field: field, Op: OpLt, Value: value}
+}
+
+func Lte(field string, value any) Filter {
+	return Filter{Field: field, Op: OpLte, Value: value}
+}
+
+func Gt(field string, value any) Filter {
+	return Filter{Field: field, Op: OpGt, Value: value}
+}
+
+func Gte(field string, value any) Filter {
+	return Filter{Field: field, Op: OpGte, Value: value}
+}
+
+func In(field string, values any) Filter {
+	return Filter{Field: field, Op: OpIn, Value: values}
+}
+
+func NotIn(field string, values any) Filter {
+	return Filter{Field: field, Op: OpNotIn, Value: values}
+}
+
+func Like(field string, pattern string) Filter {
+	return Filter{Field: field, Op: OpLike, Value: pattern}
+}
+
+func IsNull(field string) Filter {
+	return Filter{Field: field, Op: OpIsNull}
+}
+
+func NotNull(field string) Filter {
+	return Filter{Field: field, Op: OpNotNull}
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Query describes which records to select. 'After' holds values of the 'Sort'
+// fields of the last seen record and enables keyset pagination.
+type Query struct {
+	Filters     []Filter
+	Sort        []Sort
+	Limit       int
+	Offset      int
+	After       []any
+	WithDeleted bool
+}
+
+type QueryOption func(*Query)
+
+func NewQuery(opts ...QueryOption) Query {
+	var q Query
+
+	for _, opt := range opts {
+		opt(&q)
+	}
+
+	return q
+}
+
+func (q *Query) Validate() error {
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("%w: negative limit or offset", ErrInvalidQuery)
+	}
+
+	if len(q.After) > 0 && len(q.After) != len(q.Sort) {
+		return fmt.Errorf("%w: cursor has %d values for %d sort fields", ErrInvalidQuery, len(q.After), len(q.Sort))
+	}
+
+	if len(q.After) > 0 && q.Offset > 0 {
+		return fmt.Errorf("%w: cursor and offset are mutually exclusive", ErrInvalidQuery)
+	}
+
+	for _, f := range q.Filters {
+		switch f.Op {
+		case OpEq, OpNotEq, OpLt, OpLte, OpGt, OpGte, OpIn, OpNotIn, OpLike, OpIsNull, OpNotNull:
+		default:
+			return fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, f.Op)
+		}
+	}
+
+	return nil
+}
+
+// SortedBy makes sure query is sorted by 'fields' at least, appending the
+// missing ones in ascending order. It is used to make keyset pagination stable.
+func (q *Query) SortedBy(fields ...string) {
+	for _, field := range fields {
+		if !slices.ContainsFunc(q.Sort, func(s Sort) bool { return s.Field == field }) {
+			q.Sort = append(q.Sort, Sort{Field: field})
+		}
+	}
+}
+
+func Where(filters ...Filter) QueryOption {
+	return func(q *Query) {
+		q.Filters = append(q.Filters, filters...)
+	}
+}
+
+func OrderBy(field string) QueryOption {
+	return func(q *Query) {
+		q.Sort = append(q.Sort, Sort{Field: field})
+	}
+}
+
+func OrderByDesc(field string) QueryOption {
+	return func(q *Query) {
+		q.Sort = append(q.Sort, Sort{Field: field, Desc: true})
+	}
+}
+
+func Limit(n int) QueryOption {
+	return func(q *Query) {
+		q.Limit = n
+	}
+}
+
+func Offset(n int) QueryOption {
+	return func(q *Query) {
+		q.Offset = n
+	}
+}
+
+// After continues listing after the record with given sort values, see Page.Next.
+func After(cursor ...any) QueryOption {
+	return func(q *Query) {
+		q.After = cursor
+	}
+}
+
+// WithDeleted includes soft deleted records.
+func WithDeleted() QueryOption {
+	return func(q *Query) {
+		q.WithDeleted = true
+	}
+}
+
+// Page is a single page of records. 'Next' is the cursor to pass to After
+// to get the following page and is nil on the last page.
+type Page[T any] struct {
+	Items []T
+	Next  []any
+}
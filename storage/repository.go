@@ -0,0 +1,20 @@
+package storage
+
+import "context"
+
+type Repository[T any] interface {
+	Get(ctx context.Context, id any, opts ...QueryOption) (*T, error)
+	List(ctx context.Context, opts ...QueryOption) ([]T, error)
+	Page(ctx context.Context, opts ...QueryOption) (Page[T], error)
+	Count(ctx context.Context, opts ...QueryOption) (int64, error)
+	Create(ctx context.Context, entity *T) error
+	Update(ctx context.Context, entity *T) error
+	Upsert(ctx context.Context, entity *T) error
+	Delete(ctx context.Context, id any) error
+}
+
+// Transactor runs 'fn' in a transaction. Repositories called with the context
+// passed to 'fn' take part in that transaction.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,23 @@
+package storage
+
+// VersionField is the column used for optimistic locking.
+const VersionField = "version"
+
+// Versioned is embedded into models to enable optimistic locking:
+// updates only succeed if the stored version matches and then increment it.
+type Versioned struct {
+	Version int64 `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+func (v *Versioned) CurrentVersion() int64 {
+	return v.Version
+}
+
+func (v *Versioned) SetVersion(version int64) {
+	v.Version = version
+}
+
+type VersionedRecord interface {
+	CurrentVersion() int64
+	SetVersion(version int64)
+}
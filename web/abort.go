@@ -1,25 +1,80 @@
 package web
 
 import (
+	"context"
+	"encoding/xml"
 	"errors"
 	"net/http"
+
+	"github.com/google/uuid"
+
+	whctx "github.com/bohdanch-w/wheel/context"
 )
 
-func Abort(w http.ResponseWriter, err error) error {
-	w.Header().Set("Content-Type", "application/json")
+// Abort responds with the status of 'err' and {"error": "..."} body in the media type negotiated
+// from the Accept header of 'r', falling back to JSON if none is acceptable or 'r' is nil.
+func Abort(w http.ResponseWriter, r *http.Request, err error) error {
+	body := errorResponse{Error: err.Error()}
+
+	if r == nil {
+		return Respond(w, errorStatus(err), body) // nolint: wrapcheck
+	}
+
+	mediaType, enc, ok := DefaultEncoders.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		mediaType, enc = MediaTypeJSON, encodeJSON
+	}
+
+	varyAccept(w.Header())
+
+	return respond(w, errorStatus(err), mediaType, enc, body)
+}
+
+// AbortProblem responds with RFC 9457 problem details, using the transaction ID from ctx as the instance.
+func AbortProblem(ctx context.Context, w http.ResponseWriter, err error) error {
+	problem := map[string]any{}
 
-	var (
-		webErr *WebError
-		code   = http.StatusInternalServerError
-	)
+	var webErr *WebError
 
 	if errors.As(err, &webErr) {
-		code = webErr.Code
+		for k, v := range webErr.Details {
+			problem[k] = v
+		}
+	}
+
+	status := errorStatus(err)
+
+	problem["type"] = "about:blank"
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	problem["detail"] = err.Error()
+
+	if webErr != nil && webErr.Type != "" {
+		problem["type"] = webErr.Type
+	}
+
+	if webErr != nil && webErr.Title != "" {
+		problem["title"] = webErr.Title
+	}
+
+	if id := whctx.TransactionID(ctx); id != uuid.Nil {
+		problem["instance"] = "urn:uuid:" + id.String()
+	}
+
+	return respond(w, status, MediaTypeProblemJSON, encodeJSON, problem)
+}
+
+func errorStatus(err error) int {
+	var webErr *WebError
+
+	if errors.As(err, &webErr) && webErr.Code > 0 {
+		return webErr.Code
 	}
 
-	return Respond(w, code, errorResponse{Error: webErr.Error()}) // nolint: wrapcheck
+	return http.StatusInternalServerError
 }
 
 type errorResponse struct {
-	Error string `json:"error"`
+	XMLName xml.Name `json:"-" xml:"error"`
+	Error   string   `json:"error" xml:"message"`
 }
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+func TestAbort(t *testing.T) {
+	err := fmt.Errorf("get user: %w", NewError(http.StatusNotFound, errors.New("user not found")))
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{accept: "", contentType: MediaTypeJSON, body: `{"error":"get user: user not found"}`},
+		{accept: MediaTypeJSON, contentType: MediaTypeJSON, body: `{"error":"get user: user not found"}`},
+		{
+			accept:      "application/xml, application/json;q=0.5",
+			contentType: MediaTypeXML,
+			body:        xml.Header + `<error><message>get user: user not found</message></error>`,
+		},
+		// nothing acceptable, the error is still sent
+		{accept: "text/csv", contentType: MediaTypeJSON, body: `{"error":"get user: user not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tt.accept)
+
+			w := httptest.NewRecorder()
+
+			require.NoError(t, Abort(w, r, err))
+			require.Equal(t, http.StatusNotFound, w.Code)
+			require.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			require.Equal(t, []string{"Accept"}, w.Header().Values("Vary"))
+			require.Equal(t, tt.body, strings.TrimSpace(w.Body.String()))
+		})
+	}
+
+	t.Run("without request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		require.NoError(t, Abort(w, nil, errors.New("boom")))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, MediaTypeJSON, w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"error": "boom"}`, w.Body.String())
+	})
+}
+
+func TestAbortProblem(t *testing.T) {
+	problem := func(ctx context.Context, err error) (int, map[string]any) {
+		w := httptest.NewRecorder()
+
+		require.NoError(t, AbortProblem(ctx, w, err))
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var body map[string]any
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+		return w.Code, body
+	}
+
+	t.Run("web error", func(t *testing.T) {
+		id := uuid.New()
+
+		status, body := problem(whctx.WithTransactionID(context.Background(), id), &WebError{
+			Code:  http.StatusConflict,
+			Err:   errors.New("email is taken"),
+			Type:  "https://example.com/problems/email-taken",
+			Title: "Email taken",
+			// members of the problem itself are not overridden by details
+			Details: map[string]any{"email": "bob@example.com", "status": 200},
+		})
+		require.Equal(t, http.StatusConflict, status)
+		require.Equal(t, map[string]any{
+			"type":     "https://example.com/problems/email-taken",
+			"title":    "Email taken",
+			"status":   float64(http.StatusConflict),
+			"detail":   "email is taken",
+			"instance": "urn:uuid:" + id.String(),
+			"email":    "bob@example.com",
+		}, body)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		status, body := problem(context.Background(), errors.New("boom"))
+		require.Equal(t, http.StatusInternalServerError, status)
+		require.Equal(t, map[string]any{
+			"type":   "about:blank",
+			"title":  "Internal Server Error",
+			"status": float64(http.StatusInternalServerError),
+			"detail": "boom",
+		}, body)
+	})
+}
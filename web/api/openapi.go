@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bohdanch-w/wheel/web"
+)
+
+const (
+	openAPIVersion  = "3.0.3"
+	errorSchemaName = "Error"
+)
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+var pathVariable = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*(\{[^{}]*\}[^{}]*)*)?\}`)
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type OpenAPIOpt func(*openAPIConfig)
+
+type openAPIConfig struct {
+	problemErrors bool
+}
+
+// OpenAPI generates the document describing routes registered with RegisterRoute, including groups.
+// Parameters and bodies are reflected from Route.Request and Route.Response, see web.Decode for tags.
+func (r *Router) OpenAPI(info OpenAPIInfo, opts ...OpenAPIOpt) *OpenAPIDocument {
+	var cfg openAPIConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	gen := newSchemaGenerator()
+	errorContent := web.MediaTypeJSON
+	// reserved before routes, so response types named Error get qualified names
+	gen.components[errorSchemaName] = gen.objectSchema(reflect.TypeOf(errorBody{}), nil)
+
+	if cfg.problemErrors {
+		errorContent = web.MediaTypeProblemJSON
+		gen.components[errorSchemaName] = gen.objectSchema(reflect.TypeOf(problemBody{}), nil)
+		// problem details may have extension members, e.g. web.WebError details
+		gen.components[errorSchemaName].AdditionalProperties = &Schema{}
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+	}
+
+	for _, route := range r.rootRouter().routes {
+		path := pathVariable.ReplaceAllString(route.Path, "{$1}")
+
+		methods := route.Methods
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
+		}
+
+		for _, method := range methods {
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*Operation)
+			}
+
+			op := operation(gen, route, method, errorContent)
+			if len(methods) > 1 && op.OperationID != "" {
+				op.OperationID += "_" + strings.ToLower(method)
+			}
+
+			doc.Paths[path][strings.ToLower(method)] = op
+		}
+	}
+
+	doc.Components.Schemas = gen.components
+
+	return doc
+}
+
+// RegisterOpenAPIRoute serves the generated OpenAPI document as JSON on 'route', which needs only
+// Name, Path and Mid, e.g. &api.Route{Name: "openapi", Path: "/openapi.json"}.
+func (r *Router) RegisterOpenAPIRoute(route *Route, info OpenAPIInfo, opts ...OpenAPIOpt) {
+	route.Methods = []string{http.MethodGet}
+	route.Handler = func(_ context.Context, w http.ResponseWriter, _ *http.Request) error {
+		return web.Respond(w, http.StatusOK, r.OpenAPI(info, opts...)) // nolint: wrapcheck
+	}
+
+	r.RegisterRoute(route)
+}
+
+// WithProblemErrors describes error responses as RFC 9457 problem details,
+// for routes wrapped with ErrorMid using ErrorFormatProblem.
+func WithProblemErrors() OpenAPIOpt {
+	return func(cfg *openAPIConfig) {
+		cfg.problemErrors = true
+	}
+}
+
+func operation(gen *schemaGenerator, route *Route, method, errorContent string) *Operation {
+	op := &Operation{
+		OperationID: route.Name,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Responses: map[string]*Response{
+			"default": {
+				Description: "Error",
+				Content: map[string]MediaType{
+					errorContent: {Schema: &Schema{Ref: "#/components/schemas/" + errorSchemaName}},
+				},
+			},
+		},
+	}
+
+	for _, name := range pathVariable.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+
+	if route.Request != nil {
+		addRequest(gen, op, reflect.TypeOf(route.Request), method)
+	}
+
+	switch resp := route.Response; {
+	case resp == nil || reflect.TypeOf(resp) == reflect.TypeOf(NoContent{}):
+		op.Responses["204"] = &Response{Description: "No Content"}
+	default:
+		status := "200"
+
+		if coder, ok := resp.(StatusCoder); ok {
+			status = strconv.Itoa(coder.Status())
+		}
+
+		op.Responses[status] = &Response{
+			Description: "OK",
+			Content:     jsonContent(gen.schema(reflect.TypeOf(resp))),
+		}
+	}
+
+	return op
+}
+
+// addRequest adds parameters and body of the request type 't', the body is optional if 't' is a pointer.
+func addRequest(gen *schemaGenerator, op *Operation, t reflect.Type, method string) {
+	optional := t.Kind() == reflect.Pointer
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	fields := reflect.VisibleFields(t)
+
+	for _, sf := range fields {
+		for _, in := range []string{"path", "query", "header"} {
+			name, _, _ := strings.Cut(sf.Tag.Get(in), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			schema := gen.schema(sf.Type)
+			required := applyValidation(schema, sf.Tag.Get("validate")) || in == "path"
+
+			// path parameters are already known from the route, the type is more precise
+			op.Parameters = slices.DeleteFunc(op.Parameters, func(p Parameter) bool {
+				return p.In == in && p.Name == name
+			})
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: in, Required: required, Schema: schema})
+		}
+	}
+
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete {
+		return
+	}
+
+	content := make(map[string]MediaType)
+
+	body := gen.objectSchema(t, []string{"path", "query", "header", "form"})
+	if len(body.Properties) > 0 {
+		content[web.MediaTypeJSON] = MediaType{Schema: body}
+	}
+
+	form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for _, sf := range fields {
+		name, _, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema := &Schema{Type: "string", Format: "binary"}
+		if sf.Type != fileHeaderType {
+			schema = gen.schema(sf.Type)
+		}
+
+		form.Properties[name] = schema
+
+		if applyValidation(schema, sf.Tag.Get("validate")) {
+			form.Required = append(form.Required, name)
+		}
+	}
+
+	if len(form.Properties) > 0 {
+		content["multipart/form-data"] = MediaType{Schema: form}
+	}
+
+	if len(content) > 0 {
+		op.RequestBody = &RequestBody{Required: !optional, Content: content}
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{web.MediaTypeJSON: {Schema: schema}}
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+type problemBody struct {
+	Type     string `json:"type" validate:"required"`
+	Title    string `json:"title" validate:"required"`
+	Status   int    `json:"status" validate:"required"`
+	Detail   string `json:"detail" validate:"required"`
+	Instance string `json:"instance,omitempty"`
+}
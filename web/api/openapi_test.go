@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/storage"
+)
+
+var update = flag.Bool("update", false, "update golden files") // nolint: gochecknoglobals
+
+type user struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name" validate:"required,max=50"`
+	Role      string    `json:"role" validate:"oneof=admin member"`
+	Manager   *user     `json:"manager,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Page collides with storage.Page by the flattened name.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+// Error collides with the component of error responses.
+type Error struct {
+	Code string `json:"code"`
+}
+
+type listUsersRequest struct {
+	OrgID int64  `path:"org_id"`
+	Limit int    `query:"limit" validate:"min=1,max=100"`
+	Token string `header:"X-Token" validate:"required"`
+}
+
+type createUserRequest struct {
+	OrgID int64  `path:"org_id"`
+	Name  string `json:"name" validate:"required"`
+}
+
+type patchUserRequest struct {
+	ID   int64  `path:"id"`
+	Name string `json:"name"`
+}
+
+type createdUser struct {
+	user
+}
+
+func (createdUser) Status() int {
+	return http.StatusCreated
+}
+
+func testRouter() *Router {
+	router := NewRouter()
+	orgs := router.Group("/orgs/{org_id:[0-9]+}")
+
+	orgs.RegisterRoute(&Route{
+		Name:     "listUsers",
+		Path:     "/users",
+		Methods:  []string{http.MethodGet},
+		Summary:  "List users",
+		Tags:     []string{"users"},
+		Request:  listUsersRequest{},
+		Response: storage.Page[user]{},
+	})
+	orgs.RegisterRoute(&Route{
+		Name:     "createUser",
+		Path:     "/users",
+		Methods:  []string{http.MethodPost},
+		Tags:     []string{"users"},
+		Request:  createUserRequest{},
+		Response: createdUser{},
+	})
+	orgs.RegisterRoute(&Route{
+		Name:     "searchUsers",
+		Path:     "/users/search",
+		Methods:  []string{http.MethodGet, http.MethodPost},
+		Response: Page[user]{},
+	})
+	router.RegisterRoute(&Route{
+		Name:     "lastError",
+		Path:     "/errors/last",
+		Methods:  []string{http.MethodGet},
+		Response: &Error{},
+	})
+	router.RegisterRoute(&Route{
+		Name:     "patchUser",
+		Path:     "/users/{id}",
+		Methods:  []string{http.MethodPatch},
+		Request:  (*patchUserRequest)(nil),
+		Response: user{},
+	})
+	router.RegisterRoute(&Route{
+		Name:    "deleteUser",
+		Path:    "/users/{id}",
+		Methods: []string{http.MethodDelete},
+	})
+
+	return router
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := testRouter().OpenAPI(OpenAPIInfo{Title: "Users", Version: "1.0.0"})
+
+	got, err := json.MarshalIndent(doc, "", "  ")
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "openapi.json")
+
+	if *update {
+		require.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o600))
+	}
+
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.JSONEq(t, string(want), string(got))
+
+	componentName := regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+	for name := range doc.Components.Schemas {
+		require.Regexp(t, componentName, name)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: user{}, want: "user"},
+		{value: storage.Page[user]{}, want: "PageUser"},
+		{value: Page[map[string][]*user]{}, want: "PageMapStringUser"},
+		{value: Page[Page[time.Time]]{}, want: "PagePageTime"},
+		{value: Page[int]{}, want: "PageInt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			require.Equal(t, tt.want, typeName(reflect.TypeOf(tt.value)))
+		})
+	}
+}
+
+func TestOpenAPIProblemErrors(t *testing.T) {
+	doc := testRouter().OpenAPI(OpenAPIInfo{Title: "Users", Version: "1.0.0"}, WithProblemErrors())
+
+	errResponse := doc.Paths["/users/{id}"]["delete"].Responses["default"]
+	require.Equal(t, map[string]MediaType{
+		"application/problem+json": {Schema: &Schema{Ref: "#/components/schemas/Error"}},
+	}, errResponse.Content)
+
+	problem := doc.Components.Schemas["Error"]
+	require.ElementsMatch(t, []string{"type", "title", "status", "detail"}, problem.Required)
+	require.Contains(t, problem.Properties, "instance")
+	require.NotNil(t, problem.AdditionalProperties)
+}
+
+func TestRegisterOpenAPIRoute(t *testing.T) {
+	router := testRouter()
+	router.Group("/v1").RegisterOpenAPIRoute(&Route{Name: "openapi_v1", Path: "/openapi.json"},
+		OpenAPIInfo{Title: "Users", Version: "1.0.0"})
+
+	u, err := router.URL("openapi_v1")
+	require.NoError(t, err)
+	require.Equal(t, "/v1/openapi.json", u.String())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc OpenAPIDocument
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, "Users", doc.Info.Title)
+	require.Contains(t, doc.Paths, "/v1/openapi.json")
+}
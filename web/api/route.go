@@ -1,11 +1,24 @@
 package api
 
+import "time"
+
 type Route struct {
 	Name    string
 	Path    string
 	Mid     []Middleware
 	Methods []string
 	Handler Handler
+	// Timeout limits the context passed to the handler, no limit if zero.
+	Timeout time.Duration
+
+	// Summary, Description, Tags, Request and Response only describe the route in OpenAPI document.
+	// Request and Response are values of the types handled by the route, e.g. getUserRequest{},
+	// request body is optional if Request is a pointer, e.g. (*patchUserRequest)(nil).
+	Summary     string
+	Description string
+	Tags        []string
+	Request     any
+	Response    any
 }
 
 type FileRoute struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -11,22 +12,61 @@ import (
 
 func NewRouter(mid ...Middleware) *Router {
 	return &Router{
-		mx:  mux.NewRouter(),
-		mid: mid,
+		mx:         mux.NewRouter(),
+		mid:        mid,
+		websockets: newWebsockets(),
 	}
 }
 
 type Router struct {
-	mx  *mux.Router
-	mid []Middleware
+	mx     *mux.Router
+	mid    []Middleware
+	routes []*Route
+
+	websockets *websockets
+	proxies    TrustedProxies
+
+	// root and prefix are set for groups
+	root   *Router
+	prefix string
+}
+
+// Group returns router for routes under 'prefix', which are wrapped with middleware
+// of the router, then 'mid' and then their own. Groups can be nested.
+func (r *Router) Group(prefix string, mid ...Middleware) *Router {
+	fullMid := make([]Middleware, len(r.mid), len(r.mid)+len(mid))
+
+	copy(fullMid, r.mid)
+
+	return &Router{
+		mx:     r.mx.PathPrefix(prefix).Subrouter(),
+		mid:    append(fullMid, mid...),
+		root:   r.rootRouter(),
+		prefix: r.prefix + prefix,
+	}
 }
 
 func (r *Router) RegisterRoute(route *Route) {
+	registered := *route
+	registered.Path = r.prefix + route.Path
+
+	root := r.rootRouter()
+	root.routes = append(root.routes, &registered)
+
 	handler := r.wrapMiddleware(route.Handler, route.Mid...)
 
 	h := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		if route.Timeout > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, route.Timeout)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+		}
+
 		if err := handler(ctx, w, r); err != nil {
 			return
 		}
@@ -56,6 +96,8 @@ func (r *Router) RegisterFileRoute(route *FileRoute) {
 }
 
 func (r *Router) RegisterWebsocketRoute(route *WebsocketRoute) {
+	websockets := r.rootRouter().websockets
+
 	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
 		if err != nil {
@@ -64,6 +106,16 @@ func (r *Router) RegisterWebsocketRoute(route *WebsocketRoute) {
 
 		defer c.Close()
 
+		ctx, ok := websockets.add(ctx, c)
+		if !ok {
+			msg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server shutdown")
+			_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+
+			return nil
+		}
+
+		defer websockets.remove(c)
+
 		return route.Handler(ctx, &WSRequest{Origin: r, Conn: c})
 	}
 
@@ -81,7 +133,22 @@ func (r *Router) RegisterWebsocketRoute(route *WebsocketRoute) {
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mx.ServeHTTP(w, req)
+	r.rootRouter().mx.ServeHTTP(w, req)
+}
+
+// Shutdown closes websocket connections, canceling contexts of their handlers, and waits
+// for the handlers to return. New connections are refused after it is called.
+// Run it with server.WithShutdown.
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.rootRouter().websockets.shutdown(ctx)
+}
+
+func (r *Router) rootRouter() *Router {
+	if r.root != nil {
+		return r.root
+	}
+
+	return r
 }
 
 func (r *Router) wrapMiddleware(handler Handler, mid ...Middleware) Handler {
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// traceMid adds its name to X-Trace header before calling the handler.
+type traceMid string
+
+func (m traceMid) Wrap(h Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Add("X-Trace", string(m))
+
+		return h(ctx, w, r)
+	}
+}
+
+func TestRouterGroup(t *testing.T) {
+	handler := func(name string) Handler {
+		return func(_ context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("X-Trace", name)
+			_, err := w.Write([]byte(mux.Vars(r)["org"] + mux.Vars(r)["id"]))
+
+			return err
+		}
+	}
+
+	router := NewRouter(traceMid("root"))
+	v1 := router.Group("/api", traceMid("api"))
+	orgs := v1.Group("/orgs/{org}", traceMid("orgs"), traceMid("orgs2"))
+	admin := v1.Group("/admin", traceMid("admin"))
+
+	router.RegisterRoute(&Route{Name: "root", Path: "/", Methods: []string{http.MethodGet}, Handler: handler("h")})
+	v1.RegisterRoute(&Route{Name: "status", Path: "/status", Methods: []string{http.MethodGet}, Handler: handler("h")})
+	orgs.RegisterRoute(&Route{
+		Name:    "user",
+		Path:    "/users/{id}",
+		Methods: []string{http.MethodGet},
+		Mid:     []Middleware{traceMid("route")},
+		Handler: handler("h"),
+	})
+	admin.RegisterRoute(&Route{Name: "stats", Path: "/stats", Methods: []string{http.MethodGet}, Handler: handler("h")})
+
+	tests := []struct {
+		path  string
+		trace []string
+		body  string
+	}{
+		{path: "/", trace: []string{"root", "h"}},
+		{path: "/api/status", trace: []string{"root", "api", "h"}},
+		{path: "/api/orgs/acme/users/42", trace: []string{"root", "api", "orgs", "orgs2", "route", "h"}, body: "acme42"},
+		// sibling group doesn't get middleware of the other one
+		{path: "/api/admin/stats", trace: []string{"root", "api", "admin", "h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, tt.trace, w.Header().Values("X-Trace"))
+			require.Equal(t, tt.body, w.Body.String())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/acme/users/42", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	paths := make([]string, 0, len(router.routes))
+	for _, route := range router.routes {
+		paths = append(paths, route.Path)
+	}
+
+	require.Equal(t, []string{"/", "/api/status", "/api/orgs/{org}/users/{id}", "/api/admin/stats"}, paths)
+
+	u, err := router.URL("user", "org", "acme", "id", "42")
+	require.NoError(t, err)
+	require.Equal(t, "/api/orgs/acme/users/42", u.Path)
+}
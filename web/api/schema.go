@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	typeArgPackage        = regexp.MustCompile(`[^\[\],*]*/|\w+\.`)
+	typeArgWord           = regexp.MustCompile(`\w+`)
+	invalidComponentChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// Schema is a subset of OpenAPI 3.0 schema object.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// schemaGenerator reflects Go types into schemas, named structs are stored as components.
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema { // nolint: cyclop
+	if t.Kind() == reflect.Pointer {
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+
+		return s
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() { // nolint: exhaustive
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	return &Schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.objectSchema(t, nil)
+	}
+
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// stored before fields are reflected, so recursive types refer to it
+		g.components[name] = &Schema{}
+		*g.components[name] = *g.objectSchema(t, nil)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// objectSchema returns schema of JSON fields of 't', skipping fields tagged with 'skipTags'.
+func (g *schemaGenerator) objectSchema(t reflect.Type, skipTags []string) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	g.addFields(s, t, skipTags)
+
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type, skipTags []string) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+
+		// fields of embedded structs are promoted even if their type is unexported
+		if (!sf.IsExported() && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct)) || hasAnyTag(sf, skipTags) {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			g.addFields(s, sf.Type, skipTags)
+
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fieldSchema := g.schema(sf.Type)
+		required := applyValidation(fieldSchema, sf.Tag.Get("validate"))
+
+		if opts == "string" {
+			fieldSchema = &Schema{Type: "string"}
+		}
+
+		s.Properties[name] = fieldSchema
+
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// componentName returns unique name matching '^[a-zA-Z0-9._-]+$', qualified by the package
+// if another type has the same name.
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := typeName(t)
+	if _, taken := g.components[name]; !taken {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	qualified := invalidComponentChars.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+	name = qualified
+
+	for i := 2; ; i++ {
+		if _, taken := g.components[name]; !taken {
+			return name
+		}
+
+		name = qualified + strconv.Itoa(i)
+	}
+}
+
+// typeName flattens names of generic types, e.g. Page[example.com/api.user] is PageUser.
+func typeName(t reflect.Type) string {
+	base, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return base
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(base)
+
+	for _, word := range typeArgWord.FindAllString(typeArgPackage.ReplaceAllString(args, ""), -1) {
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	return sb.String()
+}
+
+// applyValidation sets constraints from 'validate' tag, see web.Validate, and reports if value is required.
+func applyValidation(s *Schema, rules string) bool {
+	required := false
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			required = true
+		case "oneof":
+			for _, option := range strings.Fields(arg) {
+				s.Enum = append(s.Enum, option)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+
+			setLimit(s, name == "min", n)
+		}
+	}
+
+	return required
+}
+
+func setLimit(s *Schema, isMin bool, n float64) {
+	switch s.Type {
+	case "string":
+		if isMin {
+			s.MinLength = ptr(int(n))
+		} else {
+			s.MaxLength = ptr(int(n))
+		}
+	case "array":
+		if isMin {
+			s.MinItems = ptr(int(n))
+		} else {
+			s.MaxItems = ptr(int(n))
+		}
+	case "integer", "number":
+		if isMin {
+			s.Minimum = ptr(n)
+		} else {
+			s.Maximum = ptr(n)
+		}
+	}
+}
+
+func hasAnyTag(sf reflect.StructField, tags []string) bool {
+	for _, tag := range tags {
+		if v := sf.Tag.Get(tag); v != "" && v != "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/bohdanch-w/wheel/web"
+)
+
+// StatusCoder is implemented by responses setting their own status.
+type StatusCoder interface {
+	Status() int
+}
+
+// NoContent response is sent as 204 without body.
+type NoContent struct{}
+
+type TypedOpt func(*typedConfig)
+
+type typedConfig struct {
+	status     int
+	decodeOpts []web.DecodeOpt
+}
+
+// Typed adapts 'fn' to Handler: the request is decoded with web.Decode, the result is sent
+// in the media type negotiated with web.Negotiate before decoding, so unacceptable requests
+// get 406 without calling 'fn'. Status is 200 (or set by WithStatus) unless the response implements
+// StatusCoder, NoContent and nil pointer responses are sent as 204. Errors are returned to middleware.
+//
+//	Handler: api.Typed(func(ctx context.Context, req getUserRequest) (*User, error) {
+//		return users.Get(ctx, req.ID)
+//	})
+func Typed[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...TypedOpt) Handler {
+	cfg := typedConfig{status: http.StatusOK}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// rejected before 'fn' has any side effects
+		responder, err := web.Negotiate(r)
+		if err != nil {
+			return err // nolint: wrapcheck
+		}
+
+		req, err := web.Decode[Req](r, cfg.decodeOpts...)
+		if err != nil {
+			return err // nolint: wrapcheck
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if isNoContent(resp) {
+			w.WriteHeader(http.StatusNoContent)
+
+			return nil
+		}
+
+		status := cfg.status
+
+		if coder, ok := any(resp).(StatusCoder); ok {
+			status = coder.Status()
+		}
+
+		return responder.Respond(w, status, resp) // nolint: wrapcheck
+	}
+}
+
+func isNoContent(resp any) bool {
+	if _, ok := resp.(NoContent); ok {
+		return true
+	}
+
+	v := reflect.ValueOf(resp)
+
+	switch v.Kind() { // nolint: exhaustive
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// WithStatus sets the status of successful responses, e.g. 201 for creation.
+func WithStatus(status int) TypedOpt {
+	return func(cfg *typedConfig) {
+		cfg.status = status
+	}
+}
+
+func WithDecodeOpts(opts ...web.DecodeOpt) TypedOpt {
+	return func(cfg *typedConfig) {
+		cfg.decodeOpts = append(cfg.decodeOpts, opts...)
+	}
+}
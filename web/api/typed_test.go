@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/web"
+)
+
+type createItemRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type createItemResponse struct {
+	Name string `json:"name"`
+}
+
+func TestTyped(t *testing.T) {
+	var calls int
+
+	handler := Typed(func(_ context.Context, req createItemRequest) (createItemResponse, error) {
+		calls++
+
+		return createItemResponse(req), nil
+	}, WithStatus(http.StatusCreated))
+
+	serve := func(accept string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name":"box"}`))
+		r.Header.Set("Content-Type", web.MediaTypeJSON)
+		r.Header.Set("Accept", accept)
+
+		w := httptest.NewRecorder()
+
+		return w, handler(r.Context(), w, r)
+	}
+
+	t.Run("not acceptable", func(t *testing.T) {
+		calls = 0
+
+		w, err := serve("text/html")
+
+		var webErr *web.WebError
+
+		require.ErrorAs(t, err, &webErr)
+		require.Equal(t, http.StatusNotAcceptable, webErr.Code)
+		require.Zero(t, calls)
+		require.Empty(t, w.Body.String())
+	})
+
+	t.Run("negotiated", func(t *testing.T) {
+		calls = 0
+
+		w, err := serve("text/html, application/json;q=0.5")
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, web.MediaTypeJSON, w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"name":"box"}`, w.Body.String())
+	})
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrRouteNotFound = wherr.Error("route not found")
+	ErrInvalidParams = wherr.Error("params must be name and value pairs")
+)
+
+// MissingParamError is returned by URL when the route variable has no value.
+type MissingParamError struct {
+	Route string
+	Param string
+}
+
+func (e *MissingParamError) Error() string {
+	return fmt.Sprintf("route %q: missing param %q", e.Route, e.Param)
+}
+
+// URL builds path of the route named 'name' from name and value pairs of its variables.
+//
+//	u, err := router.URL("get_user", "id", "42")
+func (r *Router) URL(name string, params ...string) (*url.URL, error) {
+	route := r.rootRouter().mx.Get(name)
+	if route == nil {
+		return nil, fmt.Errorf("%w: %q", ErrRouteNotFound, name)
+	}
+
+	if len(params)%2 != 0 {
+		return nil, fmt.Errorf("route %q: %w", name, ErrInvalidParams)
+	}
+
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("route %q: %w", name, err)
+	}
+
+	for _, match := range pathVariable.FindAllStringSubmatch(tmpl, -1) {
+		if !hasParam(params, match[1]) {
+			return nil, &MissingParamError{Route: name, Param: match[1]}
+		}
+	}
+
+	u, err := route.URLPath(params...)
+	if err != nil {
+		return nil, fmt.Errorf("route %q: %w", name, err)
+	}
+
+	return u, nil
+}
+
+// AbsoluteURL is URL resolved against the base URL of 'req', e.g. for Location headers and links.
+// X-Forwarded-* headers are only used for requests from proxies set with TrustProxies.
+func (r *Router) AbsoluteURL(req *http.Request, name string, params ...string) (*url.URL, error) {
+	u, err := r.URL(name, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	base := r.rootRouter().proxies.BaseURL(req)
+	base.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+
+	return base, nil
+}
+
+// TrustProxies makes AbsoluteURL honour X-Forwarded-* headers of requests from 'proxies'.
+func (r *Router) TrustProxies(proxies TrustedProxies) {
+	r.rootRouter().proxies = proxies
+}
+
+// TrustedProxies are networks of reverse proxies whose X-Forwarded-* headers are honoured.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDRs or single addresses, e.g. "10.0.0.0/8" or "127.0.0.1".
+func ParseTrustedProxies(cidrs ...string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("parse trusted proxy %q: %w", cidr, err)
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+func (p TrustedProxies) trusted(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BaseURL returns scheme, host and path prefix the client used to reach the server. If the request
+// comes from a trusted proxy, valid X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Prefix
+// headers are taken into account, using the values added by that proxy.
+func (p TrustedProxies) BaseURL(req *http.Request) *url.URL {
+	u := BaseURL(req)
+
+	if !p.trusted(req) {
+		return u
+	}
+
+	if proto := strings.ToLower(lastForwarded(req, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		u.Scheme = proto
+	}
+
+	if host := lastForwarded(req, "X-Forwarded-Host"); validHost(host) {
+		u.Host = host
+	}
+
+	if prefix := lastForwarded(req, "X-Forwarded-Prefix"); prefix != "" && !strings.ContainsAny(prefix, "?#\\") {
+		u.Path = path.Clean("/" + prefix)
+		if u.Path == "/" {
+			u.Path = ""
+		}
+	}
+
+	return u
+}
+
+// BaseURL returns scheme and host of 'req' as received by the server, ignoring X-Forwarded-* headers
+// which any client can set. See TrustedProxies.BaseURL for servers behind reverse proxies.
+func BaseURL(req *http.Request) *url.URL {
+	u := &url.URL{Scheme: "http", Host: req.Host}
+
+	if req.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	return u
+}
+
+func hasParam(params []string, name string) bool {
+	for i := 0; i < len(params); i += 2 {
+		if params[i] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lastForwarded returns the value added by the proxy closest to the server.
+func lastForwarded(req *http.Request, header string) string {
+	values := req.Header.Values(header)
+	if len(values) == 0 {
+		return ""
+	}
+
+	last := values[len(values)-1]
+
+	return strings.TrimSpace(last[strings.LastIndex(last, ",")+1:])
+}
+
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+
+	u, err := url.Parse("//" + host)
+
+	return err == nil && u.Host == host && u.User == nil && u.Path == ""
+}
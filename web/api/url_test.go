@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func urlTestRouter() *Router {
+	noop := func(context.Context, http.ResponseWriter, *http.Request) error { return nil }
+
+	router := NewRouter()
+	router.Group("/orgs/{org}").RegisterRoute(&Route{Name: "get_user", Path: "/users/{id}", Handler: noop})
+	router.RegisterRoute(&Route{Name: "health", Path: "/healthz", Handler: noop})
+
+	return router
+}
+
+func TestRouterURL(t *testing.T) {
+	router := urlTestRouter()
+
+	u, err := router.URL("get_user", "org", "acme", "id", "42")
+	require.NoError(t, err)
+	require.Equal(t, "/orgs/acme/users/42", u.String())
+
+	u, err = router.URL("health")
+	require.NoError(t, err)
+	require.Equal(t, "/healthz", u.String())
+
+	_, err = router.URL("unknown")
+	require.ErrorIs(t, err, ErrRouteNotFound)
+
+	_, err = router.URL("get_user", "org")
+	require.ErrorIs(t, err, ErrInvalidParams)
+
+	var missing *MissingParamError
+
+	_, err = router.URL("get_user", "org", "acme")
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, MissingParamError{Route: "get_user", Param: "id"}, *missing)
+}
+
+func TestBaseURL(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "::1")
+	require.NoError(t, err)
+
+	_, err = ParseTrustedProxies("proxy.local")
+	require.Error(t, err)
+
+	forwarded := map[string]string{
+		"X-Forwarded-Proto":  "https",
+		"X-Forwarded-Host":   "api.example.com",
+		"X-Forwarded-Prefix": "/v1/",
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		headers    map[string]string
+		proxies    TrustedProxies
+		want       string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "http://internal:8080",
+		},
+		{
+			name:       "direct tls",
+			remoteAddr: "192.0.2.1:1234",
+			tls:        true,
+			want:       "https://internal:8080",
+		},
+		{
+			name:       "forwarded headers without trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    forwarded,
+			want:       "http://internal:8080",
+		},
+		{
+			name:       "forwarded headers from untrusted client",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    forwarded,
+			proxies:    proxies,
+			want:       "http://internal:8080",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    forwarded,
+			proxies:    proxies,
+			want:       "https://api.example.com/v1",
+		},
+		{
+			name:       "trusted ipv6 proxy",
+			remoteAddr: "[::1]:1234",
+			headers:    forwarded,
+			proxies:    proxies,
+			want:       "https://api.example.com/v1",
+		},
+		{
+			name:       "value added by the proxy",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "http, https",
+				"X-Forwarded-Host":  "evil.example.com, api.example.com",
+			},
+			proxies: proxies,
+			want:    "https://api.example.com",
+		},
+		{
+			name:       "invalid forwarded values",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				"X-Forwarded-Proto":  "javascript",
+				"X-Forwarded-Host":   "evil.example.com/path",
+				"X-Forwarded-Prefix": "/v1?next=x",
+			},
+			proxies: proxies,
+			want:    "http://internal:8080",
+		},
+		{
+			name:       "user info in host",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Forwarded-Host": "user@evil.example.com"},
+			proxies:    proxies,
+			want:       "http://internal:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://internal:8080/", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			if !tt.tls {
+				r.TLS = nil
+			} else {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+
+			require.Equal(t, tt.want, tt.proxies.BaseURL(r).String())
+
+			if tt.proxies == nil {
+				require.Equal(t, tt.want, BaseURL(r).String())
+			}
+		})
+	}
+}
+
+func TestRouterAbsoluteURL(t *testing.T) {
+	router := urlTestRouter()
+
+	r := httptest.NewRequest(http.MethodGet, "http://internal:8080/", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set("X-Forwarded-Host", "api.example.com")
+	r.Header.Set("X-Forwarded-Prefix", "/v1")
+
+	u, err := router.AbsoluteURL(r, "get_user", "org", "acme", "id", "42")
+	require.NoError(t, err)
+	require.Equal(t, "http://internal:8080/orgs/acme/users/42", u.String())
+
+	proxies, err := ParseTrustedProxies("127.0.0.1")
+	require.NoError(t, err)
+
+	router.TrustProxies(proxies)
+
+	u, err = router.AbsoluteURL(r, "get_user", "org", "acme", "id", "42")
+	require.NoError(t, err)
+	require.Equal(t, "http://api.example.com/v1/orgs/acme/users/42", u.String())
+
+	_, err = router.AbsoluteURL(r, "unknown")
+	require.ErrorIs(t, err, ErrRouteNotFound)
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -21,3 +26,95 @@ func (r *WSRequest) Write(p []byte) (n int, err error) {
 func (r *WSRequest) Close() error {
 	return r.Conn.Close()
 }
+
+// websockets tracks open connections, so they are closed on shutdown.
+type websockets struct {
+	mu     sync.Mutex
+	conns  map[*websocket.Conn]context.CancelFunc
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newWebsockets() *websockets {
+	return &websockets{conns: make(map[*websocket.Conn]context.CancelFunc)}
+}
+
+// add registers connection, the returned context is canceled on shutdown.
+func (ws *websockets) add(ctx context.Context, c *websocket.Conn) (context.Context, bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.closed {
+		return ctx, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ws.conns[c] = cancel
+	ws.wg.Add(1)
+
+	return ctx, true
+}
+
+func (ws *websockets) remove(c *websocket.Conn) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if cancel, ok := ws.conns[c]; ok {
+		cancel()
+		delete(ws.conns, c)
+		ws.wg.Done()
+	}
+}
+
+// shutdown sends close message to all connections, cancels their handlers and waits for them to return.
+func (ws *websockets) shutdown(ctx context.Context) error {
+	ws.mu.Lock()
+	ws.closed = true
+	conns := maps.Clone(ws.conns)
+	ws.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	if d, ok := ctx.Deadline(); ok {
+		deadline = d
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+
+	// a slow client must not delay close messages of the others
+	var sent sync.WaitGroup
+
+	for c, cancel := range conns {
+		sent.Add(1)
+
+		go func() {
+			defer sent.Done()
+
+			_ = c.WriteControl(websocket.CloseMessage, msg, deadline)
+
+			cancel()
+		}()
+	}
+
+	sent.Wait()
+
+	done := make(chan struct{})
+
+	go func() {
+		ws.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+
+		for c := range ws.conns {
+			_ = c.Close()
+		}
+
+		return fmt.Errorf("close websockets: %w", ctx.Err())
+	}
+}
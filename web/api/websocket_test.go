@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterShutdownWebsockets(t *testing.T) {
+	connected := make(chan struct{}, 2)
+
+	router := NewRouter()
+	router.RegisterWebsocketRoute(&WebsocketRoute{
+		Name: "events",
+		Path: "/events",
+		Handler: func(ctx context.Context, _ *WSRequest) error {
+			connected <- struct{}{}
+
+			<-ctx.Done()
+
+			return nil
+		},
+	})
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events"
+
+	dial := func() *websocket.Conn {
+		c, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+
+		_ = resp.Body.Close()
+
+		t.Cleanup(func() { _ = c.Close() })
+
+		return c
+	}
+
+	clients := []*websocket.Conn{dial(), dial()}
+
+	for range clients {
+		<-connected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, router.Shutdown(ctx))
+
+	for _, c := range clients {
+		_, _, err := c.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	}
+
+	// connections after shutdown are refused
+	_, _, err := dial().ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseServiceRestart), err)
+}
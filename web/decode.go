@@ -0,0 +1,379 @@
+package web
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrInvalidRequest     = wherr.Error("invalid request")
+	ErrValidationFailed   = wherr.Error("validation failed")
+	ErrUnsupportedContent = wherr.Error("unsupported content type")
+	ErrBodyTooLarge       = wherr.Error("request body too large")
+
+	defaultMaxBodySize = 1 << 20
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+type DecodeOpt func(*decodeConfig)
+
+type decodeConfig struct {
+	maxBodySize           int64
+	disallowUnknownFields bool
+}
+
+// Decode reads the body of 'r' according to its Content-Type (JSON, url encoded or multipart form)
+// and binds mux path variables, query values and headers into fields tagged with 'path', 'query'
+// and 'header'. Form values are bound by 'form' tag, files to *multipart.FileHeader fields.
+// The result is validated with its 'validate' tags and Validate method, if it has one.
+// Errors are WebError: 400 for malformed request, 413, 415 and 422 for failed validation,
+// with per-field errors under "fields" of the details.
+//
+//	type getUsersRequest struct {
+//		OrgID int64  `path:"org_id"`
+//		Limit int    `query:"limit" validate:"min=1,max=100"`
+//		Token string `header:"X-Token" validate:"required"`
+//	}
+func Decode[T any](r *http.Request, opts ...DecodeOpt) (T, error) {
+	cfg := decodeConfig{maxBodySize: defaultMaxBodySize}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var v T
+
+	// pointer targets, e.g. Decode[*Req], are allocated, so their fields are bound and validated
+	target := any(&v)
+
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		ptr := reflect.New(t.Elem())
+		reflect.ValueOf(&v).Elem().Set(ptr)
+
+		target = v
+	}
+
+	if err := decodeBody(r, target, &cfg); err != nil {
+		return v, err
+	}
+
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() != reflect.Struct {
+		if err := Validate(target); err != nil {
+			return v, err
+		}
+
+		return v, nil
+	}
+
+	fieldErrs := FieldErrors{}
+
+	bindValues(value, "path", fieldErrs, func(name string) ([]string, bool) {
+		s, ok := mux.Vars(r)[name]
+
+		return []string{s}, ok
+	})
+	bindValues(value, "query", fieldErrs, func(name string) ([]string, bool) {
+		s, ok := r.URL.Query()[name]
+
+		return s, ok
+	})
+	bindValues(value, "header", fieldErrs, func(name string) ([]string, bool) {
+		s, ok := r.Header[http.CanonicalHeaderKey(name)]
+
+		return s, ok
+	})
+
+	if len(fieldErrs) > 0 {
+		return v, &WebError{
+			Code:    http.StatusBadRequest,
+			Err:     fmt.Errorf("%w: %w", ErrInvalidRequest, fieldErrs),
+			Details: map[string]any{"fields": fieldErrs},
+		}
+	}
+
+	if err := Validate(target); err != nil {
+		return v, err
+	}
+
+	return v, nil
+}
+
+func decodeBody(r *http.Request, v any, cfg *decodeConfig) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+
+	mediaType := MediaTypeJSON
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			return NewError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidRequest, err))
+		}
+	}
+
+	var err error
+
+	switch {
+	case mediaType == MediaTypeJSON || strings.HasSuffix(mediaType, "+json"):
+		err = decodeJSON(r.Body, v, cfg)
+	case mediaType == "application/x-www-form-urlencoded":
+		if err = r.ParseForm(); err == nil {
+			err = bindForm(v, r.PostForm, nil)
+		}
+	case mediaType == "multipart/form-data":
+		if err = r.ParseMultipartForm(cfg.maxBodySize); err == nil {
+			err = bindForm(v, r.MultipartForm.Value, r.MultipartForm.File)
+		}
+	default:
+		return NewError(http.StatusUnsupportedMediaType, fmt.Errorf("%w: %s", ErrUnsupportedContent, mediaType))
+	}
+
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &maxBytesErr):
+		return NewError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+	case errors.As(err, new(*WebError)):
+		return err
+	}
+
+	return NewError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidRequest, err))
+}
+
+func decodeJSON(body io.Reader, v any, cfg *decodeConfig) error {
+	dec := json.NewDecoder(body)
+
+	if cfg.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *json.UnmarshalTypeError
+
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			fieldErrs := FieldErrors{typeErr.Field: "must be " + typeErr.Type.String()}
+
+			return &WebError{
+				Code:    http.StatusBadRequest,
+				Err:     fmt.Errorf("%w: %w", ErrInvalidRequest, fieldErrs),
+				Details: map[string]any{"fields": fieldErrs},
+			}
+		}
+
+		return err // nolint: wrapcheck
+	}
+
+	return nil
+}
+
+func bindForm(v any, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fieldErrs := FieldErrors{}
+
+	bindValues(value, "form", fieldErrs, func(name string) ([]string, bool) {
+		s, ok := values[name]
+
+		return s, ok
+	})
+
+	eachTagged(value, "form", func(name string, field reflect.Value) {
+		if fhs := files[name]; len(fhs) > 0 {
+			switch {
+			case field.Type() == fileHeaderType:
+				field.Set(reflect.ValueOf(fhs[0]))
+			case field.Kind() == reflect.Slice && field.Type().Elem() == fileHeaderType:
+				field.Set(reflect.ValueOf(fhs))
+			}
+		}
+	})
+
+	if len(fieldErrs) > 0 {
+		return &WebError{
+			Code:    http.StatusBadRequest,
+			Err:     fmt.Errorf("%w: %w", ErrInvalidRequest, fieldErrs),
+			Details: map[string]any{"fields": fieldErrs},
+		}
+	}
+
+	return nil
+}
+
+func bindValues(value reflect.Value, tag string, fieldErrs FieldErrors, lookup func(name string) ([]string, bool)) {
+	eachTagged(value, tag, func(name string, field reflect.Value) {
+		values, ok := lookup(name)
+		if !ok || len(values) == 0 || field.Type() == fileHeaderType {
+			return
+		}
+
+		if err := setValues(field, values); err != nil {
+			fieldErrs[name] = err.Error()
+		}
+	})
+}
+
+// eachTagged calls 'fn' for exported fields with 'tag', including the ones of embedded structs.
+func eachTagged(value reflect.Value, tag string, fn func(name string, field reflect.Value)) {
+	for i := range value.NumField() {
+		sf := value.Type().Field(i)
+		if !sf.IsExported() && !isEmbeddedStruct(sf) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+
+		switch {
+		case name != "" && name != "-":
+			fn(name, value.Field(i))
+		case isEmbeddedStruct(sf):
+			eachTagged(value.Field(i), tag, fn)
+		}
+	}
+}
+
+// isEmbeddedStruct reports if fields of 'sf' are promoted, even if its type is unexported.
+func isEmbeddedStruct(sf reflect.StructField) bool {
+	return sf.Anonymous && sf.Type.Kind() == reflect.Struct
+}
+
+func setValues(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+
+		for i, s := range values {
+			if err := setValue(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+
+		return nil
+	}
+
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, s string) error { // nolint: cyclop
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), s); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("must be duration") // nolint: err113
+		}
+
+		field.SetInt(int64(d))
+
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return errors.New("must be RFC 3339 time") // nolint: err113
+		}
+
+		field.Set(reflect.ValueOf(t))
+
+		return nil
+	}
+
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q", s) // nolint: err113
+		}
+
+		return nil
+	}
+
+	switch field.Kind() { // nolint: exhaustive
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be boolean") // nolint: err113
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be integer") // nolint: err113
+		}
+
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be non-negative integer") // nolint: err113
+		}
+
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be number") // nolint: err113
+		}
+
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type()) // nolint: err113
+	}
+
+	return nil
+}
+
+// WithMaxBodySize limits the request body, 1 MiB by default.
+func WithMaxBodySize(n int64) DecodeOpt {
+	return func(cfg *decodeConfig) {
+		cfg.maxBodySize = n
+	}
+}
+
+// DisallowUnknownFields rejects JSON bodies with fields not present in the target type.
+func DisallowUnknownFields() DecodeOpt {
+	return func(cfg *decodeConfig) {
+		cfg.disallowUnknownFields = true
+	}
+}
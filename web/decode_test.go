@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type decodePage struct {
+	Limit  int `query:"limit" validate:"min=1,max=100"`
+	Offset int `query:"offset"`
+}
+
+type decodeRequest struct {
+	decodePage
+
+	OrgID   int64         `path:"org_id"`
+	Token   string        `header:"X-Token"`
+	Tags    []string      `query:"tag"`
+	Active  *bool         `query:"active"`
+	Timeout time.Duration `query:"timeout"`
+	Since   time.Time     `query:"since"`
+	Addr    netip.Addr    `query:"addr"`
+	Name    string        `json:"name" validate:"max=5"`
+	Ratio   float64       `json:"ratio"`
+}
+
+type decodeForm struct {
+	Name  string                `form:"name" validate:"required"`
+	Count uint                  `form:"count"`
+	File  *multipart.FileHeader `form:"file"`
+}
+
+func TestDecode(t *testing.T) {
+	newRequest := func(method, target, contentType, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+
+		return r
+	}
+
+	t.Run("sources", func(t *testing.T) {
+		r := newRequest(http.MethodPost,
+			"/orgs/7?limit=10&offset=20&tag=a&tag=b&active=true&timeout=1m&since=2024-01-02T03:04:05Z&addr=10.0.0.1",
+			MediaTypeJSON, `{"name":"box","ratio":0.5}`)
+		r.Header.Set("X-Token", "secret")
+		r = mux.SetURLVars(r, map[string]string{"org_id": "7"})
+
+		req, err := Decode[decodeRequest](r)
+		require.NoError(t, err)
+		require.Equal(t, decodeRequest{
+			decodePage: decodePage{Limit: 10, Offset: 20},
+			OrgID:      7,
+			Token:      "secret",
+			Tags:       []string{"a", "b"},
+			Active:     ptrTo(true),
+			Timeout:    time.Minute,
+			Since:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Addr:       netip.MustParseAddr("10.0.0.1"),
+			Name:       "box",
+			Ratio:      0.5,
+		}, req)
+	})
+
+	t.Run("pointer target", func(t *testing.T) {
+		req, err := Decode[*decodePage](newRequest(http.MethodGet, "/?limit=5", "", ""))
+		require.NoError(t, err)
+		require.Equal(t, &decodePage{Limit: 5}, req)
+
+		_, err = Decode[*decodePage](newRequest(http.MethodGet, "/?limit=0", "", ""))
+		requireFieldErrors(t, err, http.StatusUnprocessableEntity, FieldErrors{"limit": "must be at least 1"})
+	})
+
+	t.Run("url encoded form", func(t *testing.T) {
+		form := url.Values{"name": {"box"}, "count": {"3"}}
+
+		req, err := Decode[decodeForm](newRequest(http.MethodPost, "/", "application/x-www-form-urlencoded", form.Encode()))
+		require.NoError(t, err)
+		require.Equal(t, decodeForm{Name: "box", Count: 3}, req)
+	})
+
+	t.Run("multipart form", func(t *testing.T) {
+		var body bytes.Buffer
+
+		mw := multipart.NewWriter(&body)
+		require.NoError(t, mw.WriteField("name", "box"))
+
+		fw, err := mw.CreateFormFile("file", "box.txt")
+		require.NoError(t, err)
+
+		_, err = fw.Write([]byte("content"))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		req, err := Decode[decodeForm](newRequest(http.MethodPost, "/", mw.FormDataContentType(), body.String()))
+		require.NoError(t, err)
+		require.Equal(t, "box", req.Name)
+		require.NotNil(t, req.File)
+		require.Equal(t, "box.txt", req.File.Filename)
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/?limit=1", nil)
+
+		req, err := Decode[decodeRequest](r)
+		require.NoError(t, err)
+		require.Equal(t, 1, req.Limit)
+	})
+
+	errorTests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		opts        []DecodeOpt
+		status      int
+		fields      FieldErrors
+	}{
+		{
+			name:   "invalid query values",
+			target: "/?limit=x&active=maybe&timeout=1&since=yesterday&addr=host",
+			status: http.StatusBadRequest,
+			fields: FieldErrors{
+				"limit":   "must be integer",
+				"active":  "must be boolean",
+				"timeout": "must be duration",
+				"since":   "must be RFC 3339 time",
+				"addr":    `invalid value "host"`,
+			},
+		},
+		{
+			name:        "json type mismatch",
+			contentType: MediaTypeJSON,
+			body:        `{"name": 1}`,
+			status:      http.StatusBadRequest,
+			fields:      FieldErrors{"name": "must be string"},
+		},
+		{
+			name:        "malformed json",
+			contentType: MediaTypeJSON,
+			body:        `{"name"`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "unknown field",
+			contentType: MediaTypeJSON,
+			body:        `{"nickname": "box"}`,
+			opts:        []DecodeOpt{DisallowUnknownFields()},
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "body too large",
+			contentType: MediaTypeJSON,
+			body:        `{"name": "` + strings.Repeat("x", 100) + `"}`,
+			opts:        []DecodeOpt{WithMaxBodySize(10)},
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/csv",
+			body:        "name\nbox",
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "malformed content type",
+			contentType: "application/",
+			body:        "{}",
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "validation",
+			target:      "/?limit=1000",
+			contentType: MediaTypeJSON,
+			body:        `{"name": "boxes!"}`,
+			status:      http.StatusUnprocessableEntity,
+			fields:      FieldErrors{"limit": "must be at most 100", "name": "must have at most 5 characters"},
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+
+			_, err := Decode[decodeRequest](newRequest(http.MethodPost, target, tt.contentType, tt.body), tt.opts...)
+			requireFieldErrors(t, err, tt.status, tt.fields)
+		})
+	}
+}
+
+func requireFieldErrors(t *testing.T, err error, status int, fields FieldErrors) {
+	t.Helper()
+
+	var webErr *WebError
+
+	require.ErrorAs(t, err, &webErr)
+	require.Equal(t, status, webErr.Code)
+
+	if fields != nil {
+		require.Equal(t, map[string]any{"fields": fields}, webErr.Details)
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
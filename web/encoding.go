@@ -0,0 +1,195 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrNotAcceptable = wherr.Error("none of the accepted media types is supported")
+
+	MediaTypeJSON    = "application/json"
+	MediaTypeXML     = "application/xml"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeCBOR    = "application/cbor"
+	// MediaTypeProblemJSON is the media type of RFC 9457 problem details sent by AbortProblem.
+	MediaTypeProblemJSON = "application/problem+json"
+)
+
+// EncoderFunc writes 'v' encoded in its media type.
+type EncoderFunc func(w io.Writer, v any) error
+
+// DefaultEncoders are used by RespondTo and Abort, JSON is preferred when client accepts anything.
+var DefaultEncoders = defaultEncoders() // nolint: gochecknoglobals
+
+func defaultEncoders() *Encoders {
+	e := NewEncoders()
+
+	e.Register(MediaTypeJSON, encodeJSON)
+	e.Register(MediaTypeXML, encodeXML)
+	e.Register(MediaTypeMsgPack, encodeMsgPack)
+	e.Register(MediaTypeCBOR, encodeCBOR)
+
+	return e
+}
+
+func NewEncoders() *Encoders {
+	return &Encoders{encoders: make(map[string]EncoderFunc)}
+}
+
+// Encoders is a registry of encoders by media type, in order of server preference.
+type Encoders struct {
+	mu       sync.RWMutex
+	types    []string
+	encoders map[string]EncoderFunc
+}
+
+func (e *Encoders) Register(mediaType string, enc EncoderFunc) *Encoders {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+
+	if _, ok := e.encoders[mediaType]; !ok {
+		e.types = append(e.types, mediaType)
+	}
+
+	e.encoders[mediaType] = enc
+
+	return e
+}
+
+// Negotiate selects the media type with the highest quality in the Accept header,
+// preferring registration order on ties. Empty header accepts anything.
+func (e *Encoders) Negotiate(accept string) (string, EncoderFunc, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ranges := parseAccept(accept)
+
+	var (
+		best    string
+		bestQ   float64
+		matched bool
+	)
+
+	for _, mediaType := range e.types {
+		q, ok := acceptQuality(ranges, mediaType)
+		if ok && q > 0 && (!matched || q > bestQ) {
+			best, bestQ, matched = mediaType, q, true
+		}
+	}
+
+	if !matched {
+		return "", nil, false
+	}
+
+	return best, e.encoders[best], true
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	if strings.TrimSpace(accept) == "" {
+		return []mediaRange{{mediaType: "*/*", q: 1}}
+	}
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns quality of the most specific range matching the media type.
+func acceptQuality(ranges []mediaRange, mediaType string) (float64, bool) {
+	var (
+		q           float64
+		specificity = -1
+	)
+
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	for _, r := range ranges {
+		s := -1
+
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == typ+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q, specificity >= 0
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(v) // nolint: wrapcheck
+}
+
+func encodeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	err := xml.NewEncoder(w).Encode(v)
+
+	var unsupported *xml.UnsupportedTypeError
+
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%w: %w", ErrNotAcceptable, err)
+	}
+
+	return err // nolint: wrapcheck
+}
+
+func encodeMsgPack(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+
+	return enc.Encode(v) // nolint: wrapcheck
+}
+
+func encodeCBOR(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v) // nolint: wrapcheck
+}
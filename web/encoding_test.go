@@ -0,0 +1,156 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type encodedItem struct {
+	XMLName xml.Name `json:"-" msgpack:"-" cbor:"-" xml:"item"`
+	Name    string   `json:"name" xml:"name"`
+	Count   int      `json:"count" xml:"count"`
+}
+
+type failingXML struct{}
+
+func (failingXML) MarshalXML(*xml.Encoder, xml.StartElement) error {
+	return errors.New("broken")
+}
+
+func TestRespondTo(t *testing.T) {
+	item := encodedItem{Name: "box", Count: 3}
+
+	decoders := map[string]func(data []byte, v any) error{
+		MediaTypeJSON: json.Unmarshal,
+		MediaTypeXML:  xml.Unmarshal,
+		MediaTypeMsgPack: func(data []byte, v any) error {
+			dec := msgpack.NewDecoder(bytes.NewReader(data))
+			dec.SetCustomStructTag("json")
+
+			return dec.Decode(v)
+		},
+		MediaTypeCBOR: cbor.Unmarshal,
+	}
+
+	for mediaType, decode := range decoders {
+		t.Run(mediaType, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", mediaType)
+
+			w := httptest.NewRecorder()
+
+			require.NoError(t, RespondTo(w, r, http.StatusCreated, item))
+			require.Equal(t, http.StatusCreated, w.Code)
+			require.Equal(t, mediaType, w.Header().Get("Content-Type"))
+			require.Equal(t, []string{"Accept"}, w.Header().Values("Vary"))
+
+			var got encodedItem
+
+			require.NoError(t, decode(w.Body.Bytes(), &got))
+			require.Equal(t, item.Name, got.Name)
+			require.Equal(t, item.Count, got.Count)
+		})
+	}
+
+	t.Run("not acceptable", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "text/html")
+
+		w := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+		err := RespondTo(w, r, http.StatusOK, item)
+		require.Equal(t, http.StatusNotAcceptable, errorStatus(err))
+		require.Zero(t, w.writes)
+	})
+
+	t.Run("encode failure", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", MediaTypeXML)
+
+		w := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+		err := RespondTo(w, r, http.StatusOK, failingXML{})
+		require.Error(t, err)
+		require.Equal(t, http.StatusInternalServerError, errorStatus(err))
+		require.Zero(t, w.writes)
+		require.Empty(t, w.Body.String())
+		require.Empty(t, w.Header().Get("Content-Type"))
+
+		// the response is not committed, so the error is sent properly
+		require.NoError(t, Abort(w, r, err))
+		require.Equal(t, 1, w.writes)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, MediaTypeXML, w.Header().Get("Content-Type"))
+		require.Equal(t, []string{"Accept"}, w.Header().Values("Vary"))
+		require.Contains(t, w.Body.String(), "<error><message>web: encode application/xml response")
+	})
+
+	t.Run("not representable in xml", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", MediaTypeXML)
+
+		w := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+		// maps are not supported by encoding/xml
+		err := RespondTo(w, r, http.StatusOK, map[string]any{"name": "box"})
+		require.ErrorIs(t, err, ErrNotAcceptable)
+		require.Equal(t, http.StatusNotAcceptable, errorStatus(err))
+		require.Zero(t, w.writes)
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{accept: "", want: MediaTypeJSON, ok: true},
+		{accept: "*/*", want: MediaTypeJSON, ok: true},
+		{accept: "application/*", want: MediaTypeJSON, ok: true},
+		{accept: "application/xml", want: MediaTypeXML, ok: true},
+		{accept: "application/json;q=0.5, application/cbor", want: MediaTypeCBOR, ok: true},
+		{accept: "application/*;q=0.1, application/msgpack;q=0.2", want: MediaTypeMsgPack, ok: true},
+		{accept: "*/*, application/json;q=0", want: MediaTypeXML, ok: true},
+		{accept: "text/html", ok: false},
+		{accept: "application/json;q=0", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			mediaType, enc, ok := DefaultEncoders.Negotiate(tt.accept)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.want, mediaType)
+			require.Equal(t, tt.ok, enc != nil)
+		})
+	}
+}
+
+// headerRecorder counts WriteHeader calls, httptest.ResponseRecorder doesn't expose if it was called.
+type headerRecorder struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *headerRecorder) WriteHeader(status int) {
+	w.writes++
+
+	w.ResponseRecorder.WriteHeader(status)
+}
+
+func (w *headerRecorder) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseRecorder.Write(p) // nolint: wrapcheck
+}
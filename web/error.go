@@ -1,8 +1,17 @@
 package web
 
+// StatusClientClosedRequest is the non-standard status of requests canceled by the client.
+const StatusClientClosedRequest = 499
+
 type WebError struct { // nolint: revive
 	Code int   `json:"status"`
 	Err  error `json:"error"`
+	// Type is URI identifying the problem type, Title is its short summary.
+	// Both are only used by problem details responses.
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Details are added to the problem details response as extension members.
+	Details map[string]any `json:"details,omitempty"`
 }
 
 func NewError(code int, err error) error {
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bohdanch-w/wheel/health"
+	whweb "github.com/bohdanch-w/wheel/web"
+	whapi "github.com/bohdanch-w/wheel/web/api"
+)
+
+// Health serves liveness report of the registry, 503 if any critical liveness check fails.
+// Checks registered with health.WithReadinessOnly are not run.
+func Health(registry *health.Registry) whapi.Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		report := registry.CheckLiveness(ctx)
+
+		return whweb.Respond(w, healthStatus(report.Status != health.StatusDown), report)
+	}
+}
+
+// Ready serves readiness report of all checks of the registry, 503 if any critical check fails
+// or the registry is shutting down.
+func Ready(registry *health.Registry) whapi.Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		report := registry.Check(ctx)
+
+		return whweb.Respond(w, healthStatus(report.Ready && report.Status != health.StatusDown), report)
+	}
+}
+
+// RegisterHealthRoutes registers Health as '/healthz' and Ready as '/readyz'.
+func RegisterHealthRoutes(router *whapi.Router, registry *health.Registry, mid ...whapi.Middleware) {
+	router.RegisterRoute(&whapi.Route{
+		Name:    "healthz",
+		Path:    "/healthz",
+		Methods: []string{http.MethodGet},
+		Mid:     mid,
+		Handler: Health(registry),
+	})
+	router.RegisterRoute(&whapi.Route{
+		Name:    "readyz",
+		Path:    "/readyz",
+		Methods: []string{http.MethodGet},
+		Mid:     mid,
+		Handler: Ready(registry),
+	})
+}
+
+func healthStatus(ok bool) int {
+	if ok {
+		return http.StatusOK
+	}
+
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/health"
+	whapi "github.com/bohdanch-w/wheel/web/api"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	registry := health.NewRegistry(health.WithCacheTTL(0))
+	registry.Register("loop", func(context.Context) error { return nil })
+	registry.Register("db", func(context.Context) error { return errors.New("connection refused") },
+		health.WithReadinessOnly())
+
+	router := whapi.NewRouter()
+	RegisterHealthRoutes(router, registry)
+
+	probe := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+		return w.Code, report
+	}
+
+	status, report := probe("/healthz")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"loop"}, checkNames(report))
+
+	status, report = probe("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, []string{"db", "loop"}, checkNames(report))
+	require.Equal(t, health.StatusDown, report.Checks["db"].Status)
+
+	registry.Unregister("db")
+	require.NoError(t, registry.Shutdown(context.Background()))
+
+	status, _ = probe("/healthz")
+	require.Equal(t, http.StatusOK, status)
+
+	status, report = probe("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.False(t, report.Ready)
+}
+
+func checkNames(report health.Report) []string {
+	names := make([]string, 0, len(report.Checks))
+	for name := range report.Checks {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bohdanch-w/wheel/metrics"
+	whweb "github.com/bohdanch-w/wheel/web"
+	whapi "github.com/bohdanch-w/wheel/web/api"
+)
+
+// Metrics serves metrics of the registry in the Prometheus text exposition format.
+func Metrics(registry *metrics.Registry) whapi.Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", metrics.TextContentType)
+		w.WriteHeader(http.StatusOK)
+
+		if err := registry.WriteText(w); err != nil {
+			return whweb.NewError(-1, err)
+		}
+
+		return nil
+	}
+}
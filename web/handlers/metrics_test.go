@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/metrics"
+	whapi "github.com/bohdanch-w/wheel/web/api"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("jobs_total", "Number of processed jobs.", "status").With("done").Add(2)
+
+	router := whapi.NewRouter()
+	router.RegisterRoute(&whapi.Route{
+		Name:    "metrics",
+		Path:    "/metrics",
+		Methods: []string{http.MethodGet},
+		Handler: Metrics(registry),
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, metrics.TextContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, `# HELP jobs_total Number of processed jobs.
+# TYPE jobs_total counter
+jobs_total{status="done"} 2
+`, w.Body.String())
+}
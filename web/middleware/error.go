@@ -9,7 +9,20 @@ import (
 	"github.com/bohdanch-w/wheel/web/api"
 )
 
-type ErrorMid struct{}
+type ErrorFormat uint8
+
+const (
+	// ErrorFormatJSON responds with {"error": "..."}, or its equivalent in the media type accepted by the client.
+	ErrorFormatJSON ErrorFormat = iota
+	// ErrorFormatProblem responds with RFC 9457 problem details.
+	ErrorFormatProblem
+)
+
+type ErrorMid struct {
+	Format ErrorFormat
+	// Mapping sets statuses of errors which are not web.WebError, DefaultErrorMapping if nil.
+	Mapping *ErrorMapping
+}
 
 func (mid *ErrorMid) Wrap(h api.Handler) api.Handler {
 	f := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -22,13 +35,30 @@ func (mid *ErrorMid) Wrap(h api.Handler) api.Handler {
 
 		if !errors.As(err, &webErr) {
 			webErr = &web.WebError{
-				Code: http.StatusInternalServerError,
+				Code: mid.status(err),
 				Err:  err,
 			}
 		}
 
-		return web.Abort(w, webErr) // nolint: wrapcheck
+		if mid.Format == ErrorFormatProblem {
+			return web.AbortProblem(ctx, w, webErr) // nolint: wrapcheck
+		}
+
+		return web.Abort(w, r, webErr) // nolint: wrapcheck
 	}
 
 	return f
 }
+
+func (mid *ErrorMid) status(err error) int {
+	mapping := mid.Mapping
+	if mapping == nil {
+		mapping = DefaultErrorMapping
+	}
+
+	if status, ok := mapping.Status(err); ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/bohdanch-w/wheel/storage"
+	"github.com/bohdanch-w/wheel/web"
+)
+
+// DefaultErrorMapping is used by ErrorMid without own mapping,
+// it maps storage, rate limiter and context errors.
+var DefaultErrorMapping = defaultErrorMapping() // nolint: gochecknoglobals
+
+func defaultErrorMapping() *ErrorMapping {
+	m := NewErrorMapping()
+
+	m.Register(storage.ErrRecordNotFound, http.StatusNotFound)
+	m.Register(storage.ErrUniqueConstraintViolation, http.StatusConflict)
+	m.Register(storage.ErrForeignKeyConstraintViolation, http.StatusConflict)
+	m.Register(storage.ErrStaleRecord, http.StatusConflict)
+	m.Register(storage.ErrInvalidQuery, http.StatusBadRequest)
+	m.Register(storage.ErrQueryCanceled, http.StatusGatewayTimeout)
+	m.Register(ErrRateExceeded, http.StatusTooManyRequests)
+	m.Register(context.DeadlineExceeded, http.StatusGatewayTimeout)
+	m.Register(context.Canceled, web.StatusClientClosedRequest)
+
+	return m
+}
+
+func NewErrorMapping() *ErrorMapping {
+	return &ErrorMapping{}
+}
+
+// ErrorMapping maps errors to HTTP statuses. Rules registered later take precedence.
+type ErrorMapping struct {
+	mu    sync.RWMutex
+	rules []errorRule
+}
+
+type errorRule struct {
+	match  func(err error) bool
+	status int
+}
+
+// Register maps errors matching 'target' with errors.Is to 'status'.
+func (m *ErrorMapping) Register(target error, status int) *ErrorMapping {
+	return m.RegisterFunc(func(err error) bool { return errors.Is(err, target) }, status)
+}
+
+func (m *ErrorMapping) RegisterFunc(match func(err error) bool, status int) *ErrorMapping {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = append(m.rules, errorRule{match: match, status: status})
+
+	return m
+}
+
+// RegisterErrorType maps errors matching type 'T' with errors.As to 'status'.
+func RegisterErrorType[T error](m *ErrorMapping, status int) *ErrorMapping {
+	return m.RegisterFunc(func(err error) bool {
+		var target T
+
+		return errors.As(err, &target)
+	}, status)
+}
+
+func (m *ErrorMapping) Status(err error) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rule := range slices.Backward(m.rules) {
+		if rule.match(err) {
+			return rule.status, true
+		}
+	}
+
+	return 0, false
+}
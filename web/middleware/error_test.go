@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+	"github.com/bohdanch-w/wheel/storage"
+	"github.com/bohdanch-w/wheel/web"
+)
+
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", e.limit)
+}
+
+func TestErrorMapping(t *testing.T) {
+	errBase := errors.New("base")
+	errSpecific := fmt.Errorf("specific: %w", errBase)
+
+	m := NewErrorMapping().
+		Register(errBase, http.StatusBadRequest).
+		Register(errSpecific, http.StatusConflict)
+	RegisterErrorType[*quotaError](m, http.StatusTooManyRequests)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		ok     bool
+	}{
+		{name: "registered", err: errBase, status: http.StatusBadRequest, ok: true},
+		{name: "later registration wins", err: errSpecific, status: http.StatusConflict, ok: true},
+		{name: "wrapped", err: fmt.Errorf("handler: %w", errBase), status: http.StatusBadRequest, ok: true},
+		{name: "joined", err: errors.Join(errors.New("other"), errSpecific), status: http.StatusConflict, ok: true},
+		{name: "type", err: fmt.Errorf("create: %w", &quotaError{limit: 3}), status: http.StatusTooManyRequests, ok: true},
+		{name: "not registered", err: errors.New("unknown")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ok := m.Status(tt.err)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.status, status)
+		})
+	}
+
+	// rules registered later take precedence over the earlier ones
+	m.Register(errBase, http.StatusUnprocessableEntity)
+
+	status, _ := m.Status(errSpecific)
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+}
+
+func TestErrorMid(t *testing.T) {
+	mapping := NewErrorMapping().Register(storage.ErrRecordNotFound, http.StatusGone)
+
+	serve := func(mid *ErrorMid, ctx context.Context, err error) *httptest.ResponseRecorder {
+		h := mid.Wrap(func(context.Context, http.ResponseWriter, *http.Request) error {
+			return err
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		require.NoError(t, h(ctx, w, r))
+
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		mid    *ErrorMid
+		err    error
+		status int
+		body   string
+	}{
+		{name: "default mapping", mid: &ErrorMid{}, err: storage.ErrRecordNotFound, status: http.StatusNotFound},
+		{
+			name:   "default mapping of wrapped error",
+			mid:    &ErrorMid{},
+			err:    fmt.Errorf("get user: %w", storage.ErrStaleRecord),
+			status: http.StatusConflict,
+		},
+		{name: "own mapping", mid: &ErrorMid{Mapping: mapping}, err: storage.ErrRecordNotFound, status: http.StatusGone},
+		{
+			name:   "own mapping without rule",
+			mid:    &ErrorMid{Mapping: mapping},
+			err:    storage.ErrStaleRecord,
+			status: http.StatusInternalServerError,
+		},
+		{name: "fallback", mid: &ErrorMid{}, err: errors.New("boom"), status: http.StatusInternalServerError},
+		{
+			name:   "web error",
+			mid:    &ErrorMid{},
+			err:    fmt.Errorf("wrapped: %w", web.NewError(http.StatusPaymentRequired, storage.ErrRecordNotFound)),
+			status: http.StatusPaymentRequired,
+			// the web error is sent, the wrapping context is not
+			body: storage.ErrRecordNotFound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.mid, context.Background(), tt.err)
+			require.Equal(t, tt.status, w.Code)
+			require.Equal(t, web.MediaTypeJSON, w.Header().Get("Content-Type"))
+			body := tt.body
+			if body == "" {
+				body = tt.err.Error()
+			}
+
+			require.JSONEq(t, fmt.Sprintf(`{"error": %q}`, body), w.Body.String())
+		})
+	}
+
+	t.Run("problem details", func(t *testing.T) {
+		id := uuid.New()
+		ctx := whctx.WithTransactionID(context.Background(), id)
+
+		err := &web.WebError{
+			Code:    http.StatusTooManyRequests,
+			Err:     errors.New("quota exceeded"),
+			Type:    "https://example.com/problems/quota",
+			Title:   "Quota exceeded",
+			Details: map[string]any{"limit": 3},
+		}
+
+		w := serve(&ErrorMid{Format: ErrorFormatProblem}, ctx, fmt.Errorf("create: %w", err))
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var problem map[string]any
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Equal(t, map[string]any{
+			"type":     "https://example.com/problems/quota",
+			"title":    "Quota exceeded",
+			"status":   float64(http.StatusTooManyRequests),
+			"detail":   "quota exceeded",
+			"instance": "urn:uuid:" + id.String(),
+			"limit":    float64(3),
+		}, problem)
+	})
+
+	t.Run("problem details fallback", func(t *testing.T) {
+		w := serve(&ErrorMid{Format: ErrorFormatProblem}, context.Background(), errors.New("boom"))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Internal Server Error",
+			"status": 500,
+			"detail": "boom"
+		}`, w.Body.String())
+	})
+}
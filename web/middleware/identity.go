@@ -10,23 +10,42 @@ import (
 	whctx "github.com/bohdanch-w/wheel/context"
 	"github.com/bohdanch-w/wheel/logger"
 	"github.com/bohdanch-w/wheel/web/api"
+	"github.com/bohdanch-w/wheel/web/tracing"
 )
 
+// IdentityMid sets transaction ID and W3C trace context of the request. The trace of the caller
+// from traceparent and tracestate headers is continued with a new span, otherwise a new trace
+// is started, not sampled. Use tracing.Transport to propagate them to outgoing requests.
 type IdentityMid struct {
 	Logger logger.Logger
+	// AcceptRequestID keeps X-Request-ID header of the request, any token of printable characters
+	// up to 128 long, and echoes it in the response. Request ID which is UUID is used as the transaction ID.
+	AcceptRequestID bool
 }
 
 func (mid *IdentityMid) Wrap(h api.Handler) api.Handler {
 	f := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 		var (
-			id    = uuid.New()
-			start = time.Now()
+			requestID, id = mid.requestID(w, r)
+			tc            = traceContext(r)
+			start         = time.Now()
 		)
 
 		ctx = whctx.WithTransactionID(ctx, id)
+		ctx = whctx.WithTrace(ctx, tc)
 
-		mid.Logger.
-			WithTransaction(id).
+		if requestID != "" {
+			ctx = whctx.WithRequestID(ctx, requestID)
+		}
+
+		r = r.WithContext(ctx)
+
+		log := logger.FromCtx(ctx, mid.Logger)
+		if tc.ParentSpanID.IsValid() {
+			log = log.With("parent-span-id", tc.ParentSpanID.String())
+		}
+
+		log.
 			With("method", r.Method).
 			With("at", start.Format("02-Jan-2006 15:04:05.999")).
 			Infof("Request received: %s", r.URL.String())
@@ -36,3 +55,39 @@ func (mid *IdentityMid) Wrap(h api.Handler) api.Handler {
 
 	return f
 }
+
+// requestID returns the accepted request ID, if any, and the transaction ID.
+func (mid *IdentityMid) requestID(w http.ResponseWriter, r *http.Request) (string, uuid.UUID) {
+	if !mid.AcceptRequestID {
+		return "", uuid.New()
+	}
+
+	requestID := r.Header.Get(tracing.HeaderRequestID)
+
+	id, err := uuid.Parse(requestID)
+	if err != nil || id == uuid.Nil {
+		id = uuid.New()
+	}
+
+	if !tracing.ValidRequestID(requestID) {
+		requestID = id.String()
+	}
+
+	w.Header().Set(tracing.HeaderRequestID, requestID)
+
+	return requestID, id
+}
+
+func traceContext(r *http.Request) whctx.TraceContext {
+	tc, ok := tracing.FromRequest(r)
+	if ok {
+		tc.ParentSpanID = tc.SpanID
+	} else {
+		// spans are not recorded, so the trace is not sampled
+		tc = whctx.TraceContext{TraceID: tracing.NewTraceID()}
+	}
+
+	tc.SpanID = tracing.NewSpanID()
+
+	return tc
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+	"github.com/bohdanch-w/wheel/logger"
+	"github.com/bohdanch-w/wheel/web/tracing"
+)
+
+func TestIdentityMid(t *testing.T) {
+	type identity struct {
+		requestID     string
+		transactionID uuid.UUID
+		trace         whctx.TraceContext
+	}
+
+	serve := func(headers map[string]string) (identity, http.Header) {
+		var got identity
+
+		mid := &IdentityMid{Logger: logger.NewNullLogger(), AcceptRequestID: true}
+		h := mid.Wrap(func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) error {
+			got = identity{whctx.RequestID(ctx), whctx.TransactionID(ctx), whctx.Trace(ctx)}
+
+			return nil
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, h(r.Context(), w, r))
+
+		return got, w.Header()
+	}
+
+	t.Run("token request id", func(t *testing.T) {
+		got, header := serve(map[string]string{tracing.HeaderRequestID: "req-42"})
+		require.Equal(t, "req-42", got.requestID)
+		require.NotEqual(t, uuid.Nil, got.transactionID)
+		require.Equal(t, "req-42", header.Get(tracing.HeaderRequestID))
+	})
+
+	t.Run("uuid request id", func(t *testing.T) {
+		id := uuid.New()
+
+		got, header := serve(map[string]string{tracing.HeaderRequestID: id.String()})
+		require.Equal(t, id, got.transactionID)
+		require.Equal(t, id.String(), header.Get(tracing.HeaderRequestID))
+	})
+
+	t.Run("invalid request id", func(t *testing.T) {
+		got, header := serve(map[string]string{tracing.HeaderRequestID: "bad id"})
+		require.Equal(t, got.transactionID.String(), got.requestID)
+		require.Equal(t, got.transactionID.String(), header.Get(tracing.HeaderRequestID))
+	})
+
+	t.Run("new trace", func(t *testing.T) {
+		got, _ := serve(nil)
+		require.True(t, got.trace.IsValid())
+		require.False(t, got.trace.ParentSpanID.IsValid())
+		require.Equal(t, byte(0), got.trace.Flags)
+		require.Regexp(t, `-00$`, tracing.FormatTraceparent(got.trace))
+	})
+
+	t.Run("continued trace", func(t *testing.T) {
+		got, _ := serve(map[string]string{
+			tracing.HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		})
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.trace.TraceID.String())
+		require.Equal(t, "00f067aa0ba902b7", got.trace.ParentSpanID.String())
+		require.NotEqual(t, got.trace.ParentSpanID, got.trace.SpanID)
+		require.Equal(t, tracing.FlagSampled, got.trace.Flags)
+	})
+}
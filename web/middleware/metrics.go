@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/bohdanch-w/wheel/metrics"
+	"github.com/bohdanch-w/wheel/web"
+	"github.com/bohdanch-w/wheel/web/api"
+)
+
+const unnamedRoute = "unnamed"
+
+// MetricsMid records requests in Registry by route name, method and status:
+// http_requests_total, http_requests_in_flight and http_request_duration_seconds.
+// It should wrap ErrorMid, so the recorded status is the one sent to the client.
+// Routes without a name are recorded by their path template.
+type MetricsMid struct {
+	Registry *metrics.Registry
+	// Buckets of the latency histogram in seconds, metrics.DefaultBuckets if empty.
+	Buckets []float64
+
+	init     sync.Once
+	requests *metrics.CounterVec
+	inFlight *metrics.GaugeVec
+	duration *metrics.HistogramVec
+}
+
+// Wrap panics if Registry is not set.
+func (mid *MetricsMid) Wrap(h api.Handler) api.Handler {
+	if mid.Registry == nil {
+		panic("middleware: MetricsMid requires Registry")
+	}
+
+	mid.init.Do(func() {
+		mid.requests = mid.Registry.Counter(
+			"http_requests_total", "Number of handled HTTP requests.", "route", "method", "status")
+		mid.inFlight = mid.Registry.Gauge(
+			"http_requests_in_flight", "Number of HTTP requests being handled.", "route", "method")
+		mid.duration = mid.Registry.Histogram(
+			"http_request_duration_seconds", "Latency of HTTP requests.", mid.Buckets, "route", "method", "status")
+	})
+
+	f := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var (
+			route    = routeName(r)
+			start    = time.Now()
+			inFlight = mid.inFlight.With(route, r.Method)
+			rec      = &statusRecorder{ResponseWriter: w}
+		)
+
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		err := h(ctx, rec, r)
+
+		status := strconv.Itoa(rec.statusFor(err))
+
+		mid.requests.With(route, r.Method, status).Inc()
+		mid.duration.With(route, r.Method, status).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+
+	return f
+}
+
+func routeName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unnamedRoute
+	}
+
+	if name := route.GetName(); name != "" {
+		return name
+	}
+
+	if tpl, err := route.GetPathTemplate(); err == nil {
+		return tpl
+	}
+
+	return unnamedRoute
+}
+
+// statusRecorder remembers the status written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	return rec.ResponseWriter.Write(p) // nolint: wrapcheck
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports websocket routes, hijacked connections are recorded as 101.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: hijack", http.ErrNotSupported)
+	}
+
+	rec.status = http.StatusSwitchingProtocols
+
+	return hj.Hijack() // nolint: wrapcheck
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusFor returns the written status, or the one ErrorMid would write for 'err'.
+func (rec *statusRecorder) statusFor(err error) int {
+	switch {
+	case rec.status != 0:
+		return rec.status
+	case err == nil:
+		return http.StatusOK
+	}
+
+	var webErr *web.WebError
+
+	if errors.As(err, &webErr) && webErr.Code > 0 {
+		return webErr.Code
+	}
+
+	if status, ok := DefaultErrorMapping.Status(err); ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
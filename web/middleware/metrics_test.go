@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/metrics"
+	"github.com/bohdanch-w/wheel/storage"
+	"github.com/bohdanch-w/wheel/web"
+	"github.com/bohdanch-w/wheel/web/api"
+)
+
+// hijackRecorder is a response recorder supporting Hijack.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (rec hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	server, client := net.Pipe()
+	_ = client.Close()
+
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestMetricsMid(t *testing.T) {
+	registry := metrics.NewRegistry()
+	router := api.NewRouter(&MetricsMid{Registry: registry})
+
+	exposition := func(t *testing.T) string {
+		t.Helper()
+
+		var sb strings.Builder
+
+		require.NoError(t, registry.WriteText(&sb))
+
+		return sb.String()
+	}
+
+	register := func(name, path string, handler api.Handler) {
+		router.RegisterRoute(&api.Route{Name: name, Path: path, Methods: []string{http.MethodGet}, Handler: handler})
+	}
+
+	register("create_user", "/users", func(_ context.Context, w http.ResponseWriter, _ *http.Request) error {
+		require.Contains(t, exposition(t), `http_requests_in_flight{route="create_user",method="GET"} 1`)
+
+		w.WriteHeader(http.StatusCreated)
+
+		return nil
+	})
+	register("", "/orders/{id}", func(_ context.Context, w http.ResponseWriter, _ *http.Request) error {
+		_, err := w.Write([]byte("ok"))
+
+		return err
+	})
+	register("conflict", "/conflict", func(context.Context, http.ResponseWriter, *http.Request) error {
+		return web.NewError(http.StatusConflict, errors.New("already exists"))
+	})
+	register("missing", "/missing", func(context.Context, http.ResponseWriter, *http.Request) error {
+		return storage.ErrRecordNotFound
+	})
+	register("broken", "/broken", func(context.Context, http.ResponseWriter, *http.Request) error {
+		return errors.New("broken")
+	})
+	register("ws", "/ws", func(_ context.Context, w http.ResponseWriter, _ *http.Request) error {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	})
+
+	for _, path := range []string{"/users", "/orders/1", "/orders/2", "/conflict", "/missing", "/broken", "/ws"} {
+		router.ServeHTTP(hijackRecorder{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	text := exposition(t)
+
+	for _, line := range []string{
+		`http_requests_total{route="create_user",method="GET",status="201"} 1`,
+		`http_requests_total{route="/orders/{id}",method="GET",status="200"} 2`,
+		`http_requests_total{route="conflict",method="GET",status="409"} 1`,
+		`http_requests_total{route="missing",method="GET",status="404"} 1`,
+		`http_requests_total{route="broken",method="GET",status="500"} 1`,
+		`http_requests_total{route="ws",method="GET",status="101"} 1`,
+		`http_requests_in_flight{route="create_user",method="GET"} 0`,
+		`http_request_duration_seconds_count{route="create_user",method="GET",status="201"} 1`,
+	} {
+		require.Contains(t, text, line+"\n")
+	}
+}
+
+func TestMetricsMidWithoutRegistry(t *testing.T) {
+	require.PanicsWithValue(t, "middleware: MetricsMid requires Registry", func() {
+		api.NewRouter(&MetricsMid{}).RegisterRoute(&api.Route{
+			Name:    "test",
+			Path:    "/test",
+			Handler: func(context.Context, http.ResponseWriter, *http.Request) error { return nil },
+		})
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"runtime/debug"
 
-	whctx "github.com/bohdanch-w/wheel/context"
 	wherr "github.com/bohdanch-w/wheel/errors"
 	whlogger "github.com/bohdanch-w/wheel/logger"
 	"github.com/bohdanch-w/wheel/web"
@@ -18,18 +17,15 @@ type PanicMid struct {
 
 func (mid *PanicMid) Wrap(h api.Handler) api.Handler {
 	f := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-		transactionID := whctx.TransactionID(ctx)
-
 		defer func() {
-			if r := recover(); r != nil {
+			if p := recover(); p != nil {
 				stack := debug.Stack()
 
-				mid.Logger.
-					WithTransaction(transactionID).
-					With("panic", r).
+				whlogger.FromCtx(ctx, mid.Logger).
+					With("panic", p).
 					Errorf("Request got fatal server error: %s", stack)
 
-				_ = web.Abort(w, &web.WebError{
+				_ = web.Abort(w, r, &web.WebError{
 					Code: http.StatusInternalServerError,
 					Err:  wherr.Error("fatal server error"),
 				})
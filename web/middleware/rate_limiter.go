@@ -38,11 +38,6 @@ func NewRateLimiter(
 	shut := make(chan struct{})
 	done := make(chan struct{})
 
-	cancelFunc := func() {
-		close(shut)
-		<-done
-	}
-
 	limiter := &rateLimiterMiddleware{
 		logger:         logger,
 		qps:            qps,
@@ -57,6 +52,14 @@ func NewRateLimiter(
 		opt(limiter)
 	}
 
+	cancelFunc := func() {
+		// cleaner is never started if the middleware wasn't used
+		limiter.start.Do(func() { close(done) })
+
+		close(shut)
+		<-done
+	}
+
 	return limiter, cancelFunc
 }
 
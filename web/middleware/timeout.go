@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bohdanch-w/wheel/storage"
+	"github.com/bohdanch-w/wheel/web"
+	"github.com/bohdanch-w/wheel/web/api"
+)
+
+// TimeoutMid limits requests without a route timeout to Timeout (if set) and turns
+// canceled queries into 504 when the deadline is exceeded or 499 when the client went away.
+// Responses the handler starts after the deadline are dropped in favour of 504, so ErrorMid
+// must wrap it to send one.
+// The deadline only reaches queries run with the context of the handler,
+// postgres.NewDeadlineCheck reports ones which are not.
+type TimeoutMid struct {
+	Timeout time.Duration
+}
+
+func (mid *TimeoutMid) Wrap(h api.Handler) api.Handler {
+	f := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if _, ok := ctx.Deadline(); !ok && mid.Timeout > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, mid.Timeout)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+		}
+
+		tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+
+		err := h(ctx, tw, r)
+		if tw.timedOut {
+			return web.NewError(http.StatusGatewayTimeout, cmp.Or(err, ctx.Err()))
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		canceled := errors.Is(err, storage.ErrQueryCanceled) ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded)
+
+		var webErr *web.WebError
+
+		if !canceled || errors.As(err, &webErr) {
+			return err
+		}
+
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return web.NewError(web.StatusClientClosedRequest, err)
+		}
+
+		return web.NewError(http.StatusGatewayTimeout, err)
+	}
+
+	return f
+}
+
+// timeoutWriter drops the response if it is started after the deadline.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context // nolint: containedctx
+	started  bool
+	timedOut bool
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	if tw.expired() {
+		return
+	}
+
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	return tw.ResponseWriter.Write(p) // nolint: wrapcheck
+}
+
+func (tw *timeoutWriter) Flush() {
+	if tw.expired() {
+		return
+	}
+
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := tw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: hijack", http.ErrNotSupported)
+	}
+
+	tw.started = true
+
+	return hj.Hijack() // nolint: wrapcheck
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// expired reports whether the response must be dropped, marking it started otherwise.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.started && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+	}
+
+	tw.started = true
+
+	return tw.timedOut
+}
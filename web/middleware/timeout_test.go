@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/storage"
+	"github.com/bohdanch-w/wheel/web"
+	"github.com/bohdanch-w/wheel/web/api"
+)
+
+func TestTimeoutMid(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+
+	serve := func(ctx context.Context, handler api.Handler) *httptest.ResponseRecorder {
+		router := api.NewRouter(&ErrorMid{}, &TimeoutMid{Timeout: timeout})
+		router.RegisterRoute(&api.Route{Name: "test", Path: "/test", Methods: []string{http.MethodGet}, Handler: handler})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx))
+
+		return w
+	}
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		w := serve(context.Background(), func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) error {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now().Add(timeout), deadline, timeout)
+
+			<-ctx.Done()
+
+			return fmt.Errorf("find users: %w: %w", storage.ErrQueryCanceled, ctx.Err())
+		})
+
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+	})
+
+	t.Run("client canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		w := serve(ctx, func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) error {
+			return ctx.Err()
+		})
+
+		require.Equal(t, web.StatusClientClosedRequest, w.Code)
+	})
+
+	t.Run("late write", func(t *testing.T) {
+		w := serve(context.Background(), func(ctx context.Context, w http.ResponseWriter, _ *http.Request) error {
+			<-ctx.Done()
+
+			_, err := w.Write([]byte("late"))
+			require.ErrorIs(t, err, http.ErrHandlerTimeout)
+
+			return nil
+		})
+
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+		require.NotContains(t, w.Body.String(), "late")
+	})
+
+	t.Run("written before deadline", func(t *testing.T) {
+		w := serve(context.Background(), func(ctx context.Context, w http.ResponseWriter, _ *http.Request) error {
+			w.WriteHeader(http.StatusAccepted)
+
+			<-ctx.Done()
+
+			_, err := w.Write([]byte("done"))
+
+			return err
+		})
+
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Equal(t, "done", w.Body.String())
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		w := serve(context.Background(), func(context.Context, http.ResponseWriter, *http.Request) error {
+			return storage.ErrRecordNotFound
+		})
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("route timeout", func(t *testing.T) {
+		router := api.NewRouter(&ErrorMid{}, &TimeoutMid{Timeout: time.Hour})
+		router.RegisterRoute(&api.Route{
+			Name:    "test",
+			Path:    "/test",
+			Methods: []string{http.MethodGet},
+			Timeout: timeout,
+			Handler: func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) error {
+				<-ctx.Done()
+
+				return ctx.Err()
+			},
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+	})
+}
@@ -1,24 +1,82 @@
 package web
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 )
 
 func Respond(w http.ResponseWriter, status int, v interface{}) error {
-	w.Header().Set("Content-Type", "application/json")
+	return respond(w, status, MediaTypeJSON, encodeJSON, v)
+}
 
-	w.WriteHeader(status)
+// RespondTo responds in the media type negotiated from the Accept header of 'r' using DefaultEncoders.
+// If none is acceptable nothing is written and 406 web error is returned.
+func RespondTo(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	responder, err := Negotiate(r)
+	if err != nil {
+		return err
+	}
 
-	if v == nil {
-		return nil
+	return responder.Respond(w, status, v)
+}
+
+// Negotiate selects the response media type from the Accept header of 'r' using DefaultEncoders,
+// returning 406 web error if none is acceptable. It allows rejecting a request before handling it.
+func Negotiate(r *http.Request) (*Responder, error) {
+	mediaType, enc, ok := DefaultEncoders.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		return nil, NewError(http.StatusNotAcceptable, ErrNotAcceptable)
 	}
 
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
+	return &Responder{mediaType: mediaType, enc: enc}, nil
+}
+
+// Responder responds in the negotiated media type.
+type Responder struct {
+	mediaType string
+	enc       EncoderFunc
+}
+
+func (rs *Responder) MediaType() string {
+	return rs.mediaType
+}
+
+func (rs *Responder) Respond(w http.ResponseWriter, status int, v interface{}) error {
+	varyAccept(w.Header())
+
+	return respond(w, status, rs.mediaType, rs.enc, v)
+}
+
+func varyAccept(h http.Header) {
+	if !slices.Contains(h.Values("Vary"), "Accept") {
+		h.Add("Vary", "Accept")
+	}
+}
+
+// respond encodes 'v' before writing anything, so failed encoding leaves the response
+// uncommitted for the error middleware.
+func respond(w http.ResponseWriter, status int, contentType string, enc EncoderFunc, v interface{}) error {
+	var buf bytes.Buffer
+
+	if v != nil {
+		if err := enc(&buf, v); err != nil {
+			// the value can't be represented in the negotiated media type, e.g. a map in XML
+			if errors.Is(err, ErrNotAcceptable) {
+				return NewError(http.StatusNotAcceptable, fmt.Errorf("web: encode %s response: %w", contentType, err))
+			}
+
+			return NewError(-1, fmt.Errorf("web: encode %s response: %w", contentType, err))
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	w.WriteHeader(status)
 
-	if err := enc.Encode(v); err != nil {
+	if _, err := buf.WriteTo(w); err != nil {
 		return NewError(-1, fmt.Errorf("web: write data failed: %w", err))
 	}
 
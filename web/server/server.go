@@ -0,0 +1,262 @@
+// Package server runs HTTP servers until their context is canceled,
+// e.g. by context.OSInterruptContext, and shuts them down gracefully.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+const (
+	defaultAddr            = ":8080"
+	defaultShutdownTimeout = 15 * time.Second
+)
+
+type ServerOpt func(*Server)
+
+func New(handler http.Handler, log logger.Logger, opts ...ServerOpt) *Server {
+	s := &Server{
+		handler:         handler,
+		logger:          log,
+		shutdownTimeout: defaultShutdownTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.addrs) == 0 && len(s.listeners) == 0 {
+		s.addrs = []string{defaultAddr}
+	}
+
+	return s
+}
+
+type Server struct {
+	handler         http.Handler
+	logger          logger.Logger
+	addrs           []string
+	listeners       []net.Listener
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	configure       []func(*http.Server)
+	beforeShutdown  []func(ctx context.Context) error
+	shutdownHooks   []func(ctx context.Context) error
+	onShutdown      []func(ctx context.Context) error
+}
+
+// Run serves until ctx is done or a listener fails, then stops accepting connections,
+// waits for in-flight requests up to the shutdown timeout while running WithShutdown hooks,
+// and runs OnShutdown hooks in reverse order. Errors of all steps are joined.
+func (s *Server) Run(ctx context.Context) error {
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{ // nolint: gosec
+		Handler:     s.handler,
+		BaseContext: func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
+	}
+
+	for _, fn := range s.configure {
+		fn(srv)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		serveCh = make(chan error, len(listeners))
+	)
+
+	for _, l := range listeners {
+		s.logger.Infof("Listening on %s", l.Addr())
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+				serveCh <- fmt.Errorf("serve %s: %w", l.Addr(), err)
+			}
+		}()
+	}
+
+	var errs []error
+
+	select {
+	case <-ctx.Done():
+		s.logger.Infof("Shutting down")
+	case err := <-serveCh:
+		s.logger.WithError(err).Errorf("Server failed, shutting down")
+
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, s.shutdown(srv)...)
+
+	wg.Wait()
+	close(serveCh)
+
+	for err := range serveCh {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Server) shutdown(srv *http.Server) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var (
+		errs []error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	addErr := func(err error) {
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+
+	for _, fn := range s.beforeShutdown {
+		addErr(fn(ctx))
+	}
+
+	// requests are still served, so load balancers notice the server is not ready
+	if s.drainDelay > 0 {
+		s.logger.Infof("Waiting %s before stopping listeners", s.drainDelay)
+
+		select {
+		case <-time.After(s.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	// hijacked connections aren't waited for by the server, so they are shut down at the same time
+	for _, fn := range s.shutdownHooks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := fn(ctx); err != nil {
+				addErr(fmt.Errorf("shutdown handler: %w", err))
+			}
+		}()
+	}
+
+	if err := srv.Shutdown(ctx); err != nil {
+		addErr(fmt.Errorf("shutdown server: %w", err))
+		addErr(srv.Close())
+	}
+
+	wg.Wait()
+
+	for _, fn := range slices.Backward(s.onShutdown) {
+		addErr(fn(ctx))
+	}
+
+	return errs
+}
+
+func (s *Server) listen() ([]net.Listener, error) {
+	listeners := slices.Clone(s.listeners)
+
+	for _, addr := range s.addrs {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, fmt.Errorf("listen %s: %w", addr, err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// WithAddr adds TCP address to listen on, ":8080" is used if there are no addresses nor listeners.
+func WithAddr(addr string) ServerOpt {
+	return func(s *Server) {
+		s.addrs = append(s.addrs, addr)
+	}
+}
+
+func WithListener(l net.Listener) ServerOpt {
+	return func(s *Server) {
+		s.listeners = append(s.listeners, l)
+	}
+}
+
+// WithShutdownTimeout limits graceful shutdown, remaining connections are closed after it.
+func WithShutdownTimeout(d time.Duration) ServerOpt {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithHTTPServer configures the underlying server, e.g. its timeouts or TLS.
+func WithHTTPServer(fn func(*http.Server)) ServerOpt {
+	return func(s *Server) {
+		s.configure = append(s.configure, fn)
+	}
+}
+
+// WithDrainDelay keeps serving for 'd' after shutdown starts, before listeners are closed.
+// The delay counts towards the shutdown timeout.
+func WithDrainDelay(d time.Duration) ServerOpt {
+	return func(s *Server) {
+		s.drainDelay = d
+	}
+}
+
+// BeforeShutdown adds hook called when shutdown starts, while requests are still served,
+// e.g. health.Registry.Shutdown to fail readiness probes.
+func BeforeShutdown(fn func(ctx context.Context) error) ServerOpt {
+	return func(s *Server) {
+		s.beforeShutdown = append(s.beforeShutdown, fn)
+	}
+}
+
+// WithShutdown adds hook called together with stopping the server, for resources of the handler
+// which the server doesn't wait for, e.g. api.Router.Shutdown closing websockets.
+//
+//	server.New(router, log, server.WithShutdown(router.Shutdown))
+func WithShutdown(fn func(ctx context.Context) error) ServerOpt {
+	return func(s *Server) {
+		s.shutdownHooks = append(s.shutdownHooks, fn)
+	}
+}
+
+// OnShutdown adds hook called after the server is stopped.
+func OnShutdown(fn func(ctx context.Context) error) ServerOpt {
+	return func(s *Server) {
+		s.onShutdown = append(s.onShutdown, fn)
+	}
+}
+
+// OnShutdownFunc adds hook for background goroutines stopped by cancel functions,
+// such as the one of middleware.NewRateLimiter.
+func OnShutdownFunc(cancel func()) ServerOpt {
+	return OnShutdown(func(context.Context) error {
+		cancel()
+
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bohdanch-w/wheel/logger"
+)
+
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.list = append(e.list, event)
+}
+
+func (e *events) hook(event string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		e.add(event)
+
+		return err
+	}
+}
+
+func TestServerShutdownOrder(t *testing.T) {
+	var (
+		rec     events
+		started = make(chan struct{})
+		release = make(chan struct{})
+		errHook = errors.New("hook failed")
+	)
+
+	// long lived request, e.g. a websocket, which ends when the handler is shut down
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		rec.add("request done")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := New(handler, logger.NewNullLogger(),
+		WithListener(l),
+		BeforeShutdown(rec.hook("before shutdown", nil)),
+		WithShutdown(func(ctx context.Context) error {
+			rec.add("handler shutdown")
+			close(release)
+
+			return nil
+		}),
+		OnShutdown(rec.hook("on shutdown 1", nil)),
+		OnShutdown(rec.hook("on shutdown 2", errHook)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- srv.Run(ctx)
+	}()
+
+	respErr := make(chan error, 1)
+
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String()) // nolint: noctx
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+
+		respErr <- err
+	}()
+
+	<-started
+	cancel()
+
+	require.ErrorIs(t, <-runErr, errHook)
+	require.NoError(t, <-respErr)
+	require.Equal(t, []string{
+		"before shutdown",
+		"handler shutdown",
+		"request done",
+		"on shutdown 2",
+		"on shutdown 1",
+	}, rec.list)
+}
@@ -0,0 +1,159 @@
+// Package tracing propagates W3C trace context (https://www.w3.org/TR/trace-context/)
+// between services without recording spans.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+	wherr "github.com/bohdanch-w/wheel/errors"
+)
+
+const (
+	ErrInvalidTraceparent = wherr.Error("invalid traceparent")
+
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+	HeaderRequestID   = "X-Request-ID"
+
+	// FlagSampled is set in trace flags if the caller may have recorded the trace.
+	FlagSampled byte = 0x01
+
+	traceparentVersion = "00"
+	traceparentLength  = 55
+	maxTracestateSize  = 512
+	maxRequestIDLength = 128
+)
+
+// ParseTraceparent parses the traceparent header value, the returned context has the
+// span of the caller as SpanID. Versions above 00 are parsed by their 00 prefix.
+func ParseTraceparent(header string) (whctx.TraceContext, error) {
+	var tc whctx.TraceContext
+
+	header = strings.TrimSpace(header)
+
+	if len(header) < traceparentLength || header[:2] == "ff" ||
+		(header[:2] == traceparentVersion && len(header) != traceparentLength) ||
+		(len(header) > traceparentLength && header[traceparentLength] != '-') {
+		return whctx.TraceContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceparent, header)
+	}
+
+	parts := strings.Split(header[:traceparentLength], "-")
+	if len(parts) != 4 {
+		return whctx.TraceContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceparent, header)
+	}
+
+	var (
+		version [1]byte
+		flags   [1]byte
+	)
+
+	for _, field := range []struct {
+		dst []byte
+		src string
+	}{
+		{version[:], parts[0]},
+		{tc.TraceID[:], parts[1]},
+		{tc.SpanID[:], parts[2]},
+		{flags[:], parts[3]},
+	} {
+		if !isLowerHex(field.src) || hex.EncodedLen(len(field.dst)) != len(field.src) {
+			return whctx.TraceContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceparent, header)
+		}
+
+		_, _ = hex.Decode(field.dst, []byte(field.src))
+	}
+
+	if !tc.IsValid() {
+		return whctx.TraceContext{}, fmt.Errorf("%w: zero trace or parent id", ErrInvalidTraceparent)
+	}
+
+	tc.Flags = flags[0]
+
+	return tc, nil
+}
+
+// FormatTraceparent returns the traceparent header value of 'tc' with version 00.
+func FormatTraceparent(tc whctx.TraceContext) string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// FromRequest returns the trace context of the caller from 'r' headers.
+// Tracestate is kept only with a valid traceparent, as the specification requires.
+func FromRequest(r *http.Request) (whctx.TraceContext, bool) {
+	values := r.Header.Values(HeaderTraceparent)
+	if len(values) != 1 {
+		return whctx.TraceContext{}, false
+	}
+
+	tc, err := ParseTraceparent(values[0])
+	if err != nil {
+		return whctx.TraceContext{}, false
+	}
+
+	if state := strings.Join(r.Header.Values(HeaderTracestate), ","); len(state) <= maxTracestateSize {
+		tc.State = state
+	}
+
+	return tc, true
+}
+
+// Inject sets traceparent and tracestate of 'tc' in 'h'.
+func Inject(h http.Header, tc whctx.TraceContext) {
+	h.Set(HeaderTraceparent, FormatTraceparent(tc))
+	h.Del(HeaderTracestate)
+
+	if tc.State != "" {
+		h.Set(HeaderTracestate, tc.State)
+	}
+}
+
+func NewTraceID() whctx.TraceID {
+	var id whctx.TraceID
+
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+func NewSpanID() whctx.SpanID {
+	var id whctx.SpanID
+
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+// ValidRequestID reports whether 'id' is usable as X-Request-ID: 1 to 128 printable
+// ASCII characters without spaces, so it is safe to echo and log.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
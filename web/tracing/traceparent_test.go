@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	want := whctx.TraceContext{
+		TraceID: whctx.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  whctx.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Flags:   FlagSampled,
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+		flags  byte
+	}{
+		{name: "sampled", header: "00-" + testTraceID + "-" + testSpanID + "-01", valid: true, flags: 0x01},
+		{name: "not sampled", header: "00-" + testTraceID + "-" + testSpanID + "-00", valid: true, flags: 0x00},
+		{name: "surrounding spaces", header: " 00-" + testTraceID + "-" + testSpanID + "-01 ", valid: true, flags: 0x01},
+		{name: "future version", header: "01-" + testTraceID + "-" + testSpanID + "-01", valid: true, flags: 0x01},
+		{name: "future version with fields", header: "cc-" + testTraceID + "-" + testSpanID + "-09-extra", valid: true, flags: 0x09},
+		{name: "empty", header: ""},
+		{name: "short", header: "00-" + testTraceID + "-" + testSpanID + "-0"},
+		{name: "version 00 with fields", header: "00-" + testTraceID + "-" + testSpanID + "-01-extra"},
+		{name: "future version without separator", header: "01-" + testTraceID + "-" + testSpanID + "-01extra"},
+		{name: "invalid version", header: "ff-" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "uppercase", header: "00-" + strings.ToUpper(testTraceID) + "-" + testSpanID + "-01"},
+		{name: "not hex", header: "00-" + testTraceID + "-" + testSpanID + "-0g"},
+		{name: "wrong separator", header: "00_" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "misplaced separator", header: "00-" + testTraceID[:31] + "-0" + testSpanID + "-01"},
+		{name: "zero trace id", header: "00-" + strings.Repeat("0", 32) + "-" + testSpanID + "-01"},
+		{name: "zero parent id", header: "00-" + testTraceID + "-" + strings.Repeat("0", 16) + "-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := ParseTraceparent(tt.header)
+			if !tt.valid {
+				require.ErrorIs(t, err, ErrInvalidTraceparent)
+				require.Equal(t, whctx.TraceContext{}, tc)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, want.TraceID, tc.TraceID)
+			require.Equal(t, want.SpanID, tc.SpanID)
+			require.Equal(t, tt.flags, tc.Flags)
+		})
+	}
+
+	tc, err := ParseTraceparent(FormatTraceparent(want))
+	require.NoError(t, err)
+	require.Equal(t, want, tc)
+	require.Equal(t, "00-"+testTraceID+"-"+testSpanID+"-01", FormatTraceparent(want))
+}
+
+func TestFromRequest(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	_, ok := FromRequest(r)
+	require.False(t, ok)
+
+	r.Header.Add(HeaderTraceparent, "00-"+testTraceID+"-"+testSpanID+"-00")
+	r.Header.Add(HeaderTracestate, "a=1")
+	r.Header.Add(HeaderTracestate, "b=2")
+
+	tc, ok := FromRequest(r)
+	require.True(t, ok)
+	require.Equal(t, "a=1,b=2", tc.State)
+
+	r.Header.Add(HeaderTraceparent, "00-"+testTraceID+"-"+testSpanID+"-01")
+
+	_, ok = FromRequest(r)
+	require.False(t, ok)
+}
+
+func TestValidRequestID(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"f81d4fae-7dec-11d0-a765-00a0c91e6bf6": true,
+		"req-42":                               true,
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1": true,
+		strings.Repeat("a", 128):                             true,
+		"":                                                   false,
+		strings.Repeat("a", 129):                             false,
+		"with space":                                         false,
+		"line\nbreak":                                        false,
+		"unicodé":                                            false,
+	} {
+		require.Equal(t, valid, ValidRequestID(id), id)
+	}
+}
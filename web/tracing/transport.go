@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+// Transport propagates trace context and request ID (X-Request-ID), or the transaction ID
+// if there is none, of the request context to outgoing requests. Headers already set on the request are kept.
+//
+//	client := &http.Client{Transport: &tracing.Transport{}}
+type Transport struct {
+	// Base performs the requests, http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	requestID := whctx.RequestID(ctx)
+	if id := whctx.TransactionID(ctx); requestID == "" && id != uuid.Nil {
+		requestID = id.String()
+	}
+
+	var (
+		tc           = whctx.Trace(ctx)
+		setTrace     = tc.IsValid() && req.Header.Get(HeaderTraceparent) == ""
+		setRequestID = requestID != "" && req.Header.Get(HeaderRequestID) == ""
+	)
+
+	if setTrace || setRequestID {
+		// RoundTripper must not modify the request
+		req = req.Clone(ctx)
+
+		if setTrace {
+			Inject(req.Header, tc)
+		}
+
+		if setRequestID {
+			req.Header.Set(HeaderRequestID, requestID)
+		}
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req) // nolint: wrapcheck
+}
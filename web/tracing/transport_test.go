@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	whctx "github.com/bohdanch-w/wheel/context"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTransport(t *testing.T) {
+	var sent http.Header
+
+	transport := &Transport{Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r.Header
+
+		return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+	})}
+
+	do := func(ctx context.Context, header http.Header) {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+
+		r.Header = header
+
+		_, err = transport.RoundTrip(r)
+		require.NoError(t, err)
+		require.Empty(t, r.Header.Get(HeaderRequestID), "request must not be modified")
+	}
+
+	id := uuid.New()
+	tc := whctx.TraceContext{TraceID: NewTraceID(), SpanID: NewSpanID(), State: "a=1"}
+	ctx := whctx.WithTrace(whctx.WithTransactionID(context.Background(), id), tc)
+
+	do(ctx, http.Header{})
+	require.Equal(t, id.String(), sent.Get(HeaderRequestID))
+	require.Equal(t, FormatTraceparent(tc), sent.Get(HeaderTraceparent))
+	require.Equal(t, "a=1", sent.Get(HeaderTracestate))
+
+	do(whctx.WithRequestID(ctx, "req-42"), http.Header{})
+	require.Equal(t, "req-42", sent.Get(HeaderRequestID))
+
+	do(context.Background(), http.Header{})
+	require.Empty(t, sent)
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validatable types check themselves after Decode, returning FieldErrors makes per-field details.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldErrors maps names of request fields to their problems.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+
+	for name, msg := range e {
+		msgs = append(msgs, name+": "+msg)
+	}
+
+	slices.Sort(msgs)
+
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks 'validate' tags of struct fields and then calls Validate method of 'v',
+// pass a pointer for methods with pointer receiver.
+// Supported rules are 'required', 'min=N', 'max=N' (value of numbers, length otherwise)
+// and 'oneof=a b c'. Returns 422 WebError with per-field errors.
+func Validate(v any) error {
+	fieldErrs := FieldErrors{}
+
+	if value := reflect.Indirect(reflect.ValueOf(v)); value.Kind() == reflect.Struct {
+		validateStruct(value, fieldErrs)
+	}
+
+	if len(fieldErrs) == 0 {
+		if validatable, ok := v.(Validatable); ok {
+			err := validatable.Validate()
+
+			var errs FieldErrors
+
+			switch {
+			case err == nil:
+			case errors.As(err, &errs):
+				fieldErrs = errs
+			default:
+				return &WebError{Code: http.StatusUnprocessableEntity, Err: fmt.Errorf("%w: %w", ErrValidationFailed, err)}
+			}
+		}
+	}
+
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+
+	return &WebError{
+		Code:    http.StatusUnprocessableEntity,
+		Err:     fmt.Errorf("%w: %w", ErrValidationFailed, fieldErrs),
+		Details: map[string]any{"fields": fieldErrs},
+	}
+}
+
+func validateStruct(value reflect.Value, fieldErrs FieldErrors) {
+	for i := range value.NumField() {
+		sf := value.Type().Field(i)
+		if !sf.IsExported() && !isEmbeddedStruct(sf) {
+			continue
+		}
+
+		field := value.Field(i)
+
+		if isEmbeddedStruct(sf) {
+			validateStruct(field, fieldErrs)
+
+			continue
+		}
+
+		rules := sf.Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+
+		for _, rule := range strings.Split(rules, ",") {
+			if msg := checkRule(field, rule); msg != "" {
+				fieldErrs[fieldName(sf)] = msg
+
+				break
+			}
+		}
+	}
+}
+
+func checkRule(field reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+	if name == "required" {
+		if field.IsZero() {
+			return "is required"
+		}
+
+		return ""
+	}
+
+	// optional values are only checked when set
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return ""
+		}
+
+		field = field.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return ""
+		}
+
+		n, unit := measure(field)
+
+		switch {
+		case name == "min" && n < limit && unit != "":
+			return "must have at least " + arg + " " + unit
+		case name == "min" && n < limit:
+			return "must be at least " + arg
+		case name == "max" && n > limit && unit != "":
+			return "must have at most " + arg + " " + unit
+		case name == "max" && n > limit:
+			return "must be at most " + arg
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		if !slices.Contains(options, fmt.Sprint(field.Interface())) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	}
+
+	return ""
+}
+
+// measure returns value of numbers and length of other values with its unit.
+func measure(field reflect.Value) (float64, string) {
+	switch field.Kind() { // nolint: exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), ""
+	case reflect.String:
+		return float64(len([]rune(field.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(field.Len()), "elements"
+	}
+
+	return 0, ""
+}
+
+// fieldName returns the name of the field as the client sees it.
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "path", "header"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return sf.Name
+}
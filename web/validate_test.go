@@ -0,0 +1,118 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type validatedBase struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type validated struct {
+	validatedBase
+
+	Name   string            `json:"name" validate:"required,min=2,max=4"`
+	Count  int               `json:"count" validate:"min=1,max=10"`
+	Ratio  float64           `json:"ratio" validate:"max=1"`
+	Tags   []string          `json:"tags" validate:"max=2"`
+	Labels map[string]string `json:"labels" validate:"min=1"`
+	Kind   string            `query:"kind" validate:"oneof=a b"`
+	Limit  *int              `json:"limit" validate:"min=1"`
+	Owner  *string           `json:"owner" validate:"required"`
+	Plain  string
+}
+
+func validValue() validated {
+	return validated{
+		validatedBase: validatedBase{ID: "1"},
+		Name:          "box",
+		Count:         1,
+		Labels:        map[string]string{"a": "b"},
+		Kind:          "a",
+		Owner:         ptrTo("me"),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(v *validated)
+		fields FieldErrors
+	}{
+		{name: "valid", modify: func(*validated) {}},
+		{name: "required embedded", modify: func(v *validated) { v.ID = "" }, fields: FieldErrors{"id": "is required"}},
+		{name: "required string", modify: func(v *validated) { v.Name = "" }, fields: FieldErrors{"name": "is required"}},
+		{name: "required pointer", modify: func(v *validated) { v.Owner = nil }, fields: FieldErrors{"owner": "is required"}},
+		{name: "min characters", modify: func(v *validated) { v.Name = "я" }, fields: FieldErrors{"name": "must have at least 2 characters"}},
+		{name: "max characters", modify: func(v *validated) { v.Name = "ящики" }, fields: FieldErrors{"name": "must have at most 4 characters"}},
+		{name: "multibyte length", modify: func(v *validated) { v.Name = "ящик" }},
+		{name: "min number", modify: func(v *validated) { v.Count = 0 }, fields: FieldErrors{"count": "must be at least 1"}},
+		{name: "max number", modify: func(v *validated) { v.Count = 11 }, fields: FieldErrors{"count": "must be at most 10"}},
+		{name: "max float", modify: func(v *validated) { v.Ratio = 1.5 }, fields: FieldErrors{"ratio": "must be at most 1"}},
+		{name: "max elements", modify: func(v *validated) { v.Tags = []string{"a", "b", "c"} }, fields: FieldErrors{"tags": "must have at most 2 elements"}},
+		{name: "min map elements", modify: func(v *validated) { v.Labels = nil }, fields: FieldErrors{"labels": "must have at least 1 elements"}},
+		{name: "oneof", modify: func(v *validated) { v.Kind = "c" }, fields: FieldErrors{"kind": "must be one of a, b"}},
+		{name: "optional pointer unset", modify: func(v *validated) { v.Limit = nil }},
+		{name: "optional pointer set", modify: func(v *validated) { v.Limit = ptrTo(0) }, fields: FieldErrors{"limit": "must be at least 1"}},
+		{
+			name:   "several fields",
+			modify: func(v *validated) { v.Count, v.Kind = 0, "" },
+			fields: FieldErrors{"count": "must be at least 1", "kind": "must be one of a, b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validValue()
+			tt.modify(&v)
+
+			err := Validate(v)
+			if tt.fields == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			requireFieldErrors(t, err, http.StatusUnprocessableEntity, tt.fields)
+			require.ErrorIs(t, err, ErrValidationFailed)
+		})
+	}
+}
+
+type selfValidated struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	err  error
+}
+
+func (v *selfValidated) Validate() error {
+	if v.err != nil {
+		return v.err
+	}
+
+	if v.From > v.To {
+		return FieldErrors{"to": "must not be before from"}
+	}
+
+	return nil
+}
+
+func TestValidateMethod(t *testing.T) {
+	require.NoError(t, Validate(&selfValidated{From: 1, To: 2}))
+
+	// method with pointer receiver is not called for values
+	require.NoError(t, Validate(selfValidated{From: 2, To: 1}))
+
+	err := Validate(&selfValidated{From: 2, To: 1})
+	requireFieldErrors(t, err, http.StatusUnprocessableEntity, FieldErrors{"to": "must not be before from"})
+
+	errRange := errors.New("range is too wide")
+
+	err = Validate(&selfValidated{err: errRange})
+	requireFieldErrors(t, err, http.StatusUnprocessableEntity, nil)
+	require.ErrorIs(t, err, errRange)
+}